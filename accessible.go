@@ -0,0 +1,94 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-curses/cdk/lib/paint"
+	"github.com/go-curses/ctk"
+)
+
+var (
+	// MonochromeFlag enables the accessible monochrome mode, which is also
+	// enabled when the NO_COLOR environment variable is set (see
+	// https://no-color.org)
+	MonochromeFlag = &cli.BoolFlag{
+		Name:    "gonnectian-monochrome",
+		Usage:   "use text markers and reverse/bold attributes instead of colors, also enabled by NO_COLOR",
+		EnvVars: []string{"GONNECTIAN_MONOCHROME"},
+	}
+)
+
+// NoColor reports if the NO_COLOR environment variable is set to a non-empty
+// value
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// startupTheme returns the theme to start the console with, empty for the
+// default theme. The monochrome flag always selects the monochrome theme, then
+// the theme flag and the preferred theme are used in turn; NO_COLOR selects the
+// monochrome theme only when neither is given
+func startupTheme(flagTheme, prefTheme string, monochromeFlag, noColor bool) (theme string) {
+	switch {
+	case monochromeFlag:
+		theme = "monochrome"
+	case flagTheme != "":
+		theme = flagTheme
+	case prefTheme != "":
+		theme = prefTheme
+	case noColor:
+		theme = "monochrome"
+	}
+	return
+}
+
+// MarkToggle returns the label with the text markers of an active toggle, or
+// padded to the same width when inactive
+func MarkToggle(label string, active bool) string {
+	if active {
+		return "[" + label + "]"
+	}
+	return " " + label + " "
+}
+
+// monochrome reports if the console is in the accessible monochrome mode
+func (c *CCurses) monochrome() bool {
+	return c.console.monochrome
+}
+
+// rowTheme returns the label theme of a tenant row, the cursor row is shown
+// reversed and in the monochrome mode selected rows are bold and underlined,
+// as otherwise the "[x]" marker of the select button shows the selection
+func (c *CCurses) rowTheme(cursor, selected bool) (theme paint.Theme) {
+	theme, _ = paint.GetTheme(ctk.LabelColorTheme)
+	normal := theme.Content.Normal
+	if cursor {
+		normal = normal.Reverse(true)
+	}
+	if selected && c.monochrome() {
+		normal = normal.Bold(true).Underline(true)
+	}
+	theme.Content.Normal = normal
+	theme.Content.Selected = normal
+	theme.Content.Active = normal
+	theme.Content.Prelight = normal
+	return
+}
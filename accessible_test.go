@@ -0,0 +1,46 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"testing"
+)
+
+func TestStartupTheme(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		flagTheme      string
+		prefTheme      string
+		monochromeFlag bool
+		noColor        bool
+		expected       string
+	}{
+		{name: "default"},
+		{name: "preferred", prefTheme: "light", expected: "light"},
+		{name: "flag over preferred", flagTheme: "dark", prefTheme: "light", expected: "dark"},
+		{name: "no color", noColor: true, expected: "monochrome"},
+		{name: "preferred over no color", prefTheme: "light", noColor: true, expected: "light"},
+		{name: "flag over no color", flagTheme: "dark", noColor: true, expected: "dark"},
+		{name: "monochrome", monochromeFlag: true, expected: "monochrome"},
+		{name: "monochrome over preferred", prefTheme: "light", monochromeFlag: true, expected: "monochrome"},
+		{name: "monochrome over flag", flagTheme: "dark", prefTheme: "light", monochromeFlag: true, noColor: true, expected: "monochrome"},
+	} {
+		if theme := startupTheme(test.flagTheme, test.prefTheme, test.monochromeFlag, test.noColor); theme != test.expected {
+			t.Errorf("%v: expected %q, found %q", test.name, test.expected, theme)
+		}
+	}
+}
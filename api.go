@@ -0,0 +1,449 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+const DefaultAPIListen = "127.0.0.1:8765"
+
+// MaxRequestBody is the largest request body accepted by the admin API
+const MaxRequestBody = 64 << 10
+
+var (
+	// APIListenFlag is the global flag used to serve the admin API alongside
+	// the console user interface
+	APIListenFlag = &cli.StringFlag{
+		Name:    "gonnectian-api",
+		Usage:   "serve the gonnectian admin API on the given localhost address, eg: " + DefaultAPIListen,
+		EnvVars: []string{"GONNECTIAN_API"},
+	}
+	// APITokenFlag is the global flag for the bearer token required by all
+	// admin API requests
+	APITokenFlag = &cli.StringFlag{
+		Name:    "gonnectian-api-token",
+		Usage:   "bearer token required by the gonnectian admin API",
+		EnvVars: []string{"GONNECTIAN_API_TOKEN"},
+	}
+)
+
+// AdminAPI is a localhost-only HTTP server exposing the console operations as
+// JSON endpoints, all requests require an "Authorization: Bearer <token>"
+// header:
+//
+//	GET   /api/apps                      app versions and their tenants
+//	GET   /api/tenants                   list tenants, see listTenants
+//	GET   /api/tenants/<key>             tenant record and parsed context
+//	PATCH /api/tenants/<key>             apply a change, see patchTenant
+//	GET   /api/tenants/<key>/history     context snapshots
+//	POST  /api/tenants/<key>/rollback    restore the console keys of a version
+//	GET   /metrics                       Prometheus metrics of all sources
+//
+// Requests operate on the currently selected tenant source. Secrets are never
+// included in responses. Request bodies are limited to MaxRequestBody, missing
+// tenants and context versions respond 404 and storage errors 500
+type AdminAPI struct {
+	console *CConsole
+	token   string
+	server  *http.Server
+}
+
+func NewAdminAPI(console *CConsole, token string) (api *AdminAPI, err error) {
+	if token == "" {
+		err = fmt.Errorf("the gonnectian admin API requires a token, see --%v", APITokenFlag.Name)
+		return
+	}
+	api = &AdminAPI{console: console, token: token}
+	return
+}
+
+// Start listens on the given address, which must be a loopback address, and
+// serves requests in the background
+func (a *AdminAPI) Start(addr string) (err error) {
+	if err = checkLoopback(addr); err != nil {
+		return
+	}
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		err = fmt.Errorf("error listening for admin API: %v", err)
+		return
+	}
+	a.server = &http.Server{Handler: a, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if e := a.server.Serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+			log.ErrorF("admin API server error: %v", e)
+		}
+	}()
+	log.InfoF("gonnectian admin API listening on http://%v", listener.Addr())
+	return
+}
+
+func (a *AdminAPI) Stop() {
+	if a.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = a.server.Shutdown(ctx)
+	}
+}
+
+func checkLoopback(addr string) (err error) {
+	var host string
+	if host, _, err = net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid admin API address %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("admin API address must be a loopback address: %q", addr)
+	}
+	return
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		log.ErrorF("error writing admin API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// errorStatus returns the response status of an error of the console, a
+// missing tenant or context version is not found and anything else is an
+// error of the storage
+func errorStatus(err error) (status int) {
+	switch {
+	case errors.Is(err, ErrTenantNotFound), errors.Is(err, ErrVersionNotFound), errors.Is(err, ErrHistoryDisabled):
+		status = http.StatusNotFound
+	default:
+		status = http.StatusInternalServerError
+	}
+	return
+}
+
+// bodyStatus returns the response status of an error decoding a request body
+// limited to MaxRequestBody
+func bodyStatus(err error) (status int) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func (a *AdminAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !bearer || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	// tenant is true for any path within /api/tenants/<key>
+	tenant := len(parts) >= 3 && parts[0] == "api" && parts[1] == "tenants"
+	switch {
+	case path == "metrics" && r.Method == http.MethodGet:
+		a.metrics(w, r)
+	case path == "api/apps" && r.Method == http.MethodGet:
+		a.listApps(w, r)
+	case path == "api/tenants" && r.Method == http.MethodGet:
+		a.listTenants(w, r)
+	case tenant && len(parts) == 3 && r.Method == http.MethodGet:
+		a.getTenant(w, r, parts[2])
+	case tenant && len(parts) == 3 && r.Method == http.MethodPatch:
+		a.patchTenant(w, r, parts[2])
+	case tenant && len(parts) == 4 && parts[3] == "history" && r.Method == http.MethodGet:
+		a.tenantHistory(w, r, parts[2])
+	case tenant && len(parts) == 4 && parts[3] == "rollback" && r.Method == http.MethodPost:
+		a.rollbackTenant(w, r, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found: %v %v", r.Method, r.URL.Path))
+	}
+}
+
+func (a *AdminAPI) metrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := a.console.CollectMetrics()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", MetricsContentType)
+	if err = WriteMetrics(w, metrics); err != nil {
+		log.ErrorF("error writing metrics response: %v", err)
+	}
+}
+
+type apiApp struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	URL     string   `json:"url"`
+	Tenants []string `json:"tenants"`
+}
+
+type apiApps struct {
+	Source  string   `json:"source"`
+	Apps    []apiApp `json:"apps"`
+	Unknown int      `json:"unknown"`
+}
+
+func (a *AdminAPI) listApps(w http.ResponseWriter, r *http.Request) {
+	apps, installs, unknown, err := a.console.appInstalls()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := apiApps{Source: a.console.Source().Label, Apps: []apiApp{}, Unknown: unknown}
+	for idx, app := range apps {
+		response.Apps = append(response.Apps, apiApp{
+			Name:    app.Descriptor.Name,
+			Version: app.Descriptor.Version,
+			URL:     app.URL,
+			Tenants: append([]string{}, installs[idx]...),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type apiTenants struct {
+	Source  string         `json:"source"`
+	Total   int64          `json:"total"`
+	Tenants []ExportRecord `json:"tenants"`
+}
+
+// listTenants supports the query parameters:
+//
+//	base_url=text                 tenants with a BaseURL containing the text
+//	installed=true|false          tenants with the given installed state
+//	sort=base_url,-created_at     sort fields, "-" for descending
+//	offset=N, limit=N             page of the results
+func (a *AdminAPI) listTenants(w http.ResponseWriter, r *http.Request) {
+	var err error
+	values := r.URL.Query()
+	query := TenantQuery{Filter: TenantFilter{BaseURL: values.Get("base_url")}}
+	if v := values.Get("installed"); v != "" {
+		var installed bool
+		if installed, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid installed value: %q", v))
+			return
+		}
+		query.Filter.Installed = &installed
+	}
+	if v := values.Get("sort"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			s := TenantSort{Field: TenantSortField(strings.TrimPrefix(field, "-")), Desc: strings.HasPrefix(field, "-")}
+			query.Sort = append(query.Sort, s)
+		}
+	}
+	query.Sort = append(query.Sort, TenantSort{Field: SortByClientKey})
+	for name, dst := range map[string]*int{"offset": &query.Offset, "limit": &query.Limit} {
+		if v := values.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil || *dst < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %v value: %q", name, v))
+				return
+			}
+		}
+	}
+
+	// the same source throughout, even if another is selected meanwhile
+	source := a.console.Source()
+	response := apiTenants{Source: source.Label, Tenants: []ExportRecord{}}
+	var tenants []*store.Tenant
+	if tenants, err = source.repo.List(query); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	} else if response.Total, err = source.repo.Count(query.Filter); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, tenant := range tenants {
+		response.Tenants = append(response.Tenants, NewExportRecord(tenant))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (a *AdminAPI) findTenant(w http.ResponseWriter, clientKey string) (tenant *store.Tenant, ok bool) {
+	var err error
+	if tenant, err = a.console.currentRepo().Get(clientKey); errors.Is(err, ErrTenantNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%v: %v", err, clientKey))
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+	} else {
+		ok = true
+	}
+	return
+}
+
+type apiTenant struct {
+	Tenant  ExportRecord  `json:"tenant"`
+	Context TenantContext `json:"context"`
+	App     string        `json:"app,omitempty"`
+}
+
+func (a *AdminAPI) getTenant(w http.ResponseWriter, r *http.Request, clientKey string) {
+	tenant, ok := a.findTenant(w, clientKey)
+	if !ok {
+		return
+	}
+	ctx, err := ParseTenantContext(tenant)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := apiTenant{Tenant: NewExportRecord(tenant), Context: ctx}
+	apps := a.console.appDescriptors()
+	if idx := MatchTenantApp(apps, ctx); idx >= 0 {
+		response.App = apps[idx].Label()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type apiChange struct {
+	Updated bool     `json:"updated"`
+	DryRun  bool     `json:"dryRun,omitempty"`
+	Diff    []string `json:"diff"`
+}
+
+// patchTenant applies a change, in the form of a change file entry without
+// the select, for example:
+//
+//	{"debug": {"enabled": true, "expires": "24h"}, "unset": ["old-key"]}
+//
+// The "dry_run=true" query parameter returns the diff without saving
+func (a *AdminAPI) patchTenant(w http.ResponseWriter, r *http.Request, clientKey string) {
+	change := &Change{}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(change); err != nil {
+		writeError(w, bodyStatus(err), fmt.Errorf("invalid change: %v", err))
+		return
+	} else if change.Select != (TenantSelector{}) {
+		writeError(w, http.StatusBadRequest, errors.New("invalid change: select is not supported, the tenant is given by the path"))
+		return
+	} else if change.IsEmpty() {
+		writeError(w, http.StatusBadRequest, errors.New("invalid change: no debug, unlicensed, flag, set or unset given"))
+		return
+	} else if err = change.parseExpiry(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	tenant, ok := a.findTenant(w, clientKey)
+	if !ok {
+		return
+	}
+	result, err := a.console.BatchUpdate([]*store.Tenant{tenant}, func(tenant *store.Tenant, ctx TenantContext) {
+		change.Apply(ctx)
+	}, dryRun)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	response := apiChange{Updated: len(result.Updated) > 0, DryRun: dryRun, Diff: result.Diffs[tenant.ClientKey]}
+	if response.Diff == nil {
+		response.Diff = []string{}
+	}
+	if response.Updated && !dryRun {
+		a.console.requestRefresh()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type apiSnapshot struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Note      string        `json:"note"`
+	Context   TenantContext `json:"context"`
+}
+
+func (a *AdminAPI) tenantHistory(w http.ResponseWriter, r *http.Request, clientKey string) {
+	if _, ok := a.findTenant(w, clientKey); !ok {
+		return
+	}
+	snapshots, err := a.console.currentRepo().History(clientKey)
+	if errors.Is(err, ErrHistoryDisabled) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := make([]apiSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		ctx, err := snapshot.TenantContext()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response = append(response, apiSnapshot{Version: snapshot.Version, CreatedAt: snapshot.CreatedAt, Note: snapshot.Note, Context: ctx})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// rollbackTenant restores the ConsoleContextKeys of the context version given
+// as {"version": N}
+func (a *AdminAPI) rollbackTenant(w http.ResponseWriter, r *http.Request, clientKey string) {
+	var request struct {
+		Version int `json:"version"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBody)).Decode(&request); err != nil {
+		writeError(w, bodyStatus(err), fmt.Errorf(`rollback requires {"version": N}: %v`, err))
+		return
+	} else if request.Version <= 0 {
+		writeError(w, http.StatusBadRequest, errors.New(`rollback requires {"version": N}`))
+		return
+	} else if _, ok := a.findTenant(w, clientKey); !ok {
+		return
+	}
+	diff, err := a.console.RollbackContext(clientKey, request.Version)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if diff == nil {
+		diff = []string{}
+	}
+	if len(diff) > 0 {
+		a.console.requestRefresh()
+	}
+	writeJSON(w, http.StatusOK, apiChange{Updated: len(diff) > 0, Diff: diff})
+}
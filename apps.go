@@ -0,0 +1,204 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/feature"
+	"github.com/go-enjin/be/pkg/log"
+
+	gonnectian "github.com/go-enjin/features-gonnectian"
+)
+
+// AppDescriptor is an Atlassian Connect app version as shown in the AppInfo
+// panel
+type AppDescriptor struct {
+	URL        string
+	Descriptor *gonnectian.Descriptor
+}
+
+func (a AppDescriptor) Label() (label string) {
+	return fmt.Sprintf("%v v%v", a.Descriptor.Name, a.Descriptor.Version)
+}
+
+// MatchTenantApp finds the app installed by a tenant using the descriptor key
+// and baseUrl recorded within the tenant context. The baseUrl identifies the
+// specific version, when only the key is recorded the app is returned only if
+// a single version has that key. The index returned is -1 if no app matched
+func MatchTenantApp(apps []AppDescriptor, ctx TenantContext) (idx int) {
+	if url := trimBaseURL(ctx.AppBaseURL()); url != "" {
+		for idx = range apps {
+			if trimBaseURL(apps[idx].Descriptor.BaseURL) == url {
+				return
+			}
+		}
+	}
+	idx = -1
+	if key := ctx.AppKey(); key != "" {
+		for i := range apps {
+			if apps[i].Descriptor.Key == key {
+				if idx >= 0 {
+					// more than one version of the app
+					return -1
+				}
+				idx = i
+			}
+		}
+	}
+	return
+}
+
+// appInstalls groups the BaseURLs of the tenants of the current source by the
+// index of the app they installed, counting the tenants with no known app
+func (f *CConsole) appInstalls() (apps []AppDescriptor, installs map[int][]string, unknown int, err error) {
+	apps = f.appDescriptors()
+	var tenants []*store.Tenant
+	if tenants, err = f.currentRepo().List(TenantQuery{Sort: []TenantSort{{Field: SortByBaseURL}}}); err != nil {
+		return
+	}
+	installs = make(map[int][]string)
+	for _, tenant := range tenants {
+		ctx, e := ParseTenantContext(tenant)
+		if e != nil {
+			log.ErrorF("%v - %v", tenant.BaseURL, e)
+		}
+		if idx := MatchTenantApp(apps, ctx); idx >= 0 {
+			installs[idx] = append(installs[idx], tenant.BaseURL)
+		} else {
+			unknown += 1
+		}
+	}
+	return
+}
+
+// tenantAppFinder is the part of a gonnectian.Feature used to find the app
+// installed by a tenant
+type tenantAppFinder interface {
+	GetPluginDescriptor() (descriptor *gonnectian.Descriptor)
+	FindTenantByUrl(url string) (tenant *store.Tenant)
+}
+
+// RecordTenantApps backfills the descriptor key and baseUrl of the app
+// installed by each tenant of all sources without them. The tenant records do
+// not say which app was installed, so the app is the one of the gonnectian
+// features with a tenant of the same BaseURL and ClientKey. Tenants found by
+// more than one app, or by none, are skipped. The descriptions of the apps
+// recorded and the reasons tenants were skipped are keyed by BaseURL, prefixed
+// with the source label when there is more than one source
+func (f *CConsole) RecordTenantApps(dryRun bool) (recorded, skipped map[string]string, err error) {
+	var finders []tenantAppFinder
+	for _, gf := range feature.FilterTyped[gonnectian.Feature](f.features.List()) {
+		finders = append(finders, gf)
+	}
+	recorded, skipped = make(map[string]string), make(map[string]string)
+	for _, src := range f.sources {
+		prefix := ""
+		if len(f.sources) > 1 {
+			prefix = "[" + src.Label + "] "
+		}
+		if err = recordRepositoryApps(src.repo, finders, prefix, recorded, skipped, dryRun); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// recordRepositoryApps records the apps of the tenants of the repository, see
+// RecordTenantApps. Each tenant is read again and saved within a transaction,
+// so that any other change made since the tenants were listed is kept
+func recordRepositoryApps(repo TenantRepository, finders []tenantAppFinder, prefix string, recorded, skipped map[string]string, dryRun bool) (err error) {
+	var tenants []*store.Tenant
+	if tenants, err = repo.List(TenantQuery{Sort: []TenantSort{{Field: SortByBaseURL}}}); err != nil {
+		return
+	}
+	for _, listed := range tenants {
+		var ctx TenantContext
+		if ctx, err = ParseTenantContext(listed); err != nil {
+			log.ErrorF("%v - %v", listed.BaseURL, err)
+			err = nil
+			continue
+		} else if ctx.AppKey() != "" {
+			continue
+		}
+
+		var found []*gonnectian.Descriptor
+		for _, finder := range finders {
+			// features-gonnectian returns an empty tenant when none is found
+			if tenant := finder.FindTenantByUrl(listed.BaseURL); tenant != nil && tenant.ClientKey == listed.ClientKey {
+				found = append(found, finder.GetPluginDescriptor())
+			}
+		}
+		switch len(found) {
+		case 0:
+			skipped[prefix+listed.BaseURL] = "not found by any app"
+			continue
+		case 1:
+		default:
+			var keys []string
+			for _, descriptor := range found {
+				keys = append(keys, descriptor.Key+" "+descriptor.BaseURL)
+			}
+			skipped[prefix+listed.BaseURL] = "found by more than one app: " + strings.Join(keys, ", ")
+			continue
+		}
+		descriptor := found[0]
+		if dryRun {
+			recorded[prefix+listed.BaseURL] = descriptor.Key + " " + descriptor.BaseURL
+			continue
+		}
+
+		var updated bool
+		err = repo.Transaction(func(tx TenantRepository) (err error) {
+			var tenant *store.Tenant
+			if tenant, err = tx.Get(listed.ClientKey); err != nil {
+				return
+			} else if ctx, err = ParseTenantContext(tenant); err != nil {
+				log.ErrorF("%v - %v", tenant.BaseURL, err)
+				return nil
+			} else if ctx.AppKey() != "" {
+				// recorded since the tenants were listed
+				return
+			}
+			ctx[CtxKeyAppKey] = descriptor.Key
+			ctx[CtxKeyAppBaseURL] = descriptor.BaseURL
+			if err = tx.UpdateContext(tenant, ctx); err == nil {
+				updated = true
+			}
+			return
+		})
+		if errors.Is(err, ErrTenantNotFound) {
+			// removed since the tenants were listed
+			err = nil
+			continue
+		} else if err != nil {
+			return
+		} else if updated {
+			recorded[prefix+listed.BaseURL] = descriptor.Key + " " + descriptor.BaseURL
+			log.InfoF("recorded tenant app: %v%v - %v %v", prefix, listed.BaseURL, descriptor.Key, descriptor.BaseURL)
+		}
+	}
+	return
+}
+
+func trimBaseURL(url string) string {
+	return strings.TrimRight(url, "/")
+}
@@ -0,0 +1,129 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	gonnectian "github.com/go-enjin/features-gonnectian"
+)
+
+func TestMatchTenantApp(t *testing.T) {
+	// two versions of the reports app and one of the timesheets app
+	apps := DemoApps()
+	for _, test := range []struct {
+		name     string
+		ctx      TenantContext
+		expected int
+	}{
+		{name: "nothing recorded", ctx: TenantContext{}, expected: -1},
+		{name: "base url", ctx: TenantContext{CtxKeyAppBaseURL: "https://apps.example.io/reports/v2"}, expected: 1},
+		{name: "base url trailing slash", ctx: TenantContext{CtxKeyAppBaseURL: "https://apps.example.io/reports/v1/"}, expected: 0},
+		{name: "base url before key", ctx: TenantContext{CtxKeyAppKey: "io.example.demo-timesheets", CtxKeyAppBaseURL: "https://apps.example.io/reports/v1"}, expected: 0},
+		{name: "single version key", ctx: TenantContext{CtxKeyAppKey: "io.example.demo-timesheets"}, expected: 2},
+		{name: "ambiguous key", ctx: TenantContext{CtxKeyAppKey: "io.example.demo-reports"}, expected: -1},
+		{name: "unknown base url", ctx: TenantContext{CtxKeyAppBaseURL: "https://apps.example.io/gone"}, expected: -1},
+		{name: "unknown key", ctx: TenantContext{CtxKeyAppKey: "io.example.gone"}, expected: -1},
+	} {
+		if idx := MatchTenantApp(apps, test.ctx); idx != test.expected {
+			t.Errorf("%v: expected %d, found %d", test.name, test.expected, idx)
+		}
+	}
+}
+
+// fakeAppFinder is an app with the given tenants, keyed by BaseURL
+type fakeAppFinder struct {
+	descriptor *gonnectian.Descriptor
+	tenants    map[string]*store.Tenant
+}
+
+func (f fakeAppFinder) GetPluginDescriptor() *gonnectian.Descriptor {
+	return f.descriptor
+}
+
+func (f fakeAppFinder) FindTenantByUrl(url string) *store.Tenant {
+	if tenant, ok := f.tenants[url]; ok {
+		return tenant
+	}
+	// as features-gonnectian does when the tenant is not found
+	return &store.Tenant{}
+}
+
+func TestRecordRepositoryApps(t *testing.T) {
+	apps := DemoApps()
+	tenants := []*store.Tenant{
+		{ClientKey: "key-a", BaseURL: "https://alpha.atlassian.net", Context: []byte(`{"debug":"false"}`)},
+		{ClientKey: "key-b", BaseURL: "https://beta.atlassian.net", Context: []byte(`{"debug":"false"}`)},
+		{ClientKey: "key-c", BaseURL: "https://gamma.atlassian.net", Context: []byte(`{"app-key":"io.example.demo-timesheets"}`)},
+		{ClientKey: "key-d", BaseURL: "https://delta.atlassian.net", Context: []byte(`{broken`)},
+		{ClientKey: "key-e", BaseURL: "https://epsilon.atlassian.net", Context: []byte(`{}`)},
+		{ClientKey: "key-f", BaseURL: "https://zeta.atlassian.net", Context: []byte(`{}`)},
+	}
+	finders := []tenantAppFinder{
+		fakeAppFinder{descriptor: apps[0].Descriptor, tenants: map[string]*store.Tenant{
+			"https://alpha.atlassian.net": tenants[0],
+			"https://beta.atlassian.net":  tenants[1],
+			// a reinstall with another client key
+			"https://epsilon.atlassian.net": {ClientKey: "key-x", BaseURL: "https://epsilon.atlassian.net"},
+		}},
+		fakeAppFinder{descriptor: apps[2].Descriptor, tenants: map[string]*store.Tenant{
+			"https://beta.atlassian.net":  tenants[1],
+			"https://gamma.atlassian.net": tenants[2],
+			"https://zeta.atlassian.net":  tenants[5],
+		}},
+	}
+	expectedRecorded := map[string]string{
+		"https://alpha.atlassian.net": "io.example.demo-reports https://apps.example.io/reports/v1",
+		"https://zeta.atlassian.net":  "io.example.demo-timesheets https://apps.example.io/timesheets",
+	}
+	expectedSkipped := map[string]string{
+		"https://beta.atlassian.net":    "found by more than one app: io.example.demo-reports https://apps.example.io/reports/v1, io.example.demo-timesheets https://apps.example.io/timesheets",
+		"https://epsilon.atlassian.net": "not found by any app",
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		repo := NewMemoryTenantRepository(tenants...)
+		before := contexts(t, repo)
+		recorded, skipped := make(map[string]string), make(map[string]string)
+		if err := recordRepositoryApps(repo, finders, "", recorded, skipped, dryRun); err != nil {
+			t.Fatalf("dry-run=%v: %v", dryRun, err)
+		}
+		if !reflect.DeepEqual(recorded, expectedRecorded) {
+			t.Errorf("dry-run=%v: expected recorded %v, found %v", dryRun, expectedRecorded, recorded)
+		}
+		if !reflect.DeepEqual(skipped, expectedSkipped) {
+			t.Errorf("dry-run=%v: expected skipped %v, found %v", dryRun, expectedSkipped, skipped)
+		}
+
+		after := contexts(t, repo)
+		for _, key := range []string{"key-b", "key-c", "key-d", "key-e"} {
+			if after[key] != before[key] {
+				t.Errorf("dry-run=%v: expected %v unchanged, found %v", dryRun, key, after[key])
+			}
+		}
+		tenant, _ := repo.Get("key-a")
+		ctx, _ := ParseTenantContext(tenant)
+		if dryRun && ctx.AppKey() != "" {
+			t.Errorf("expected nothing recorded by a dry run: %v", ctx)
+		} else if !dryRun && MatchTenantApp(apps, ctx) != 0 {
+			t.Errorf("expected the first app recorded: %v", ctx)
+		}
+	}
+}
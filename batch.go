@@ -0,0 +1,183 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/globals"
+)
+
+// BatchResult summarizes the outcome of a batch operation, each list contains
+// tenant ClientKeys
+type BatchResult struct {
+	Updated   []string
+	Unchanged []string
+	Deleted   []string
+
+	// Diffs are the context changes made, keyed by ClientKey
+	Diffs map[string][]string
+	// BaseURLs are the tenant BaseURLs, keyed by ClientKey
+	BaseURLs map[string]string
+}
+
+func newBatchResult() (r *BatchResult) {
+	return &BatchResult{
+		Diffs:    make(map[string][]string),
+		BaseURLs: make(map[string]string),
+	}
+}
+
+func (r *BatchResult) Summary() (text string) {
+	switch {
+	case len(r.Deleted) > 0:
+		text = fmt.Sprintf("%d tenants deleted", len(r.Deleted))
+	default:
+		text = fmt.Sprintf("%d tenants updated, %d unchanged", len(r.Updated), len(r.Unchanged))
+	}
+	return
+}
+
+// ExportRecord is the exported form of a store.Tenant, the SharedSecret is
+// deliberately omitted
+type ExportRecord struct {
+	ClientKey      string          `json:"clientKey"`
+	PublicKey      string          `json:"publicKey,omitempty"`
+	OauthClientId  string          `json:"oauthClientId,omitempty"`
+	BaseURL        string          `json:"baseUrl"`
+	ProductType    string          `json:"productType,omitempty"`
+	Description    string          `json:"description,omitempty"`
+	AddonInstalled bool            `json:"addonInstalled"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	Context        json.RawMessage `json:"context,omitempty"`
+}
+
+func NewExportRecord(tenant *store.Tenant) (record ExportRecord) {
+	record = ExportRecord{
+		ClientKey:      tenant.ClientKey,
+		PublicKey:      tenant.PublicKey,
+		OauthClientId:  tenant.OauthClientId,
+		BaseURL:        tenant.BaseURL,
+		ProductType:    tenant.ProductType,
+		Description:    tenant.Description,
+		AddonInstalled: tenant.AddonInstalled,
+		CreatedAt:      tenant.CreatedAt,
+		UpdatedAt:      tenant.UpdatedAt,
+	}
+	if len(tenant.Context) > 0 {
+		record.Context = json.RawMessage(tenant.Context)
+	}
+	return
+}
+
+func ExportTenants(w io.Writer, tenants []*store.Tenant) (err error) {
+	records := make([]ExportRecord, 0, len(tenants))
+	for _, tenant := range tenants {
+		records = append(records, NewExportRecord(tenant))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(records)
+	return
+}
+
+// ExportTenantsFile writes the tenants to a new timestamped file within the
+// system temp directory
+func ExportTenantsFile(tenants []*store.Tenant) (path string, err error) {
+	path = filepath.Join(os.TempDir(), fmt.Sprintf("%v-tenants-%v.json", globals.BinName, time.Now().Format("20060102-150405")))
+	var fh *os.File
+	if fh, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600); err != nil {
+		return
+	}
+	defer fh.Close()
+	err = ExportTenants(fh, tenants)
+	return
+}
+
+func (f *CConsole) findTenants(clientKeys []string) (tenants []*store.Tenant, err error) {
+	if len(clientKeys) == 0 {
+		return
+	}
+	tenants, err = f.currentRepo().List(TenantQuery{
+		Filter: TenantFilter{ClientKeys: clientKeys},
+		Sort:   []TenantSort{{Field: SortByBaseURL}},
+	})
+	return
+}
+
+// BatchUpdate applies fn to the context of a copy of each tenant within a
+// single transaction, if any tenant fails to save no changes are kept; dryRun
+// computes the result without saving anything. The given tenants are never
+// modified
+func (f *CConsole) BatchUpdate(tenants []*store.Tenant, fn func(tenant *store.Tenant, ctx TenantContext), dryRun bool) (result *BatchResult, err error) {
+	update := func(repo TenantRepository) (err error) {
+		result = newBatchResult()
+		for _, original := range tenants {
+			tenant := copyTenant(original)
+			result.BaseURLs[tenant.ClientKey] = tenant.BaseURL
+			var before, after TenantContext
+			if before, err = ParseTenantContext(tenant); err != nil {
+				return fmt.Errorf("%v - %v", tenant.BaseURL, err)
+			}
+			after = before.Copy()
+			fn(tenant, after)
+			diff := DiffContexts(before, after)
+			if len(diff) == 0 {
+				result.Unchanged = append(result.Unchanged, tenant.ClientKey)
+				continue
+			}
+			result.Diffs[tenant.ClientKey] = diff
+			result.Updated = append(result.Updated, tenant.ClientKey)
+			if repo == nil {
+				continue
+			} else if err = repo.UpdateContext(tenant, after); err != nil {
+				return
+			}
+		}
+		return
+	}
+	if dryRun {
+		err = update(nil)
+		return
+	}
+	err = f.currentRepo().Transaction(update)
+	return
+}
+
+// BatchDelete removes all the tenants within a single transaction
+func (f *CConsole) BatchDelete(tenants []*store.Tenant) (result *BatchResult, err error) {
+	err = f.currentRepo().Transaction(func(repo TenantRepository) (err error) {
+		result = newBatchResult()
+		for _, tenant := range tenants {
+			if err = repo.Delete(tenant.ClientKey); err != nil {
+				return
+			}
+			result.BaseURLs[tenant.ClientKey] = tenant.BaseURL
+			result.Deleted = append(result.Deleted, tenant.ClientKey)
+		}
+		return
+	})
+	return
+}
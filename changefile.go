@@ -0,0 +1,222 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+// ChangeFile describes a set of tenant context changes, parsed from YAML or
+// JSON, for example:
+//
+//	changes:
+//	  - select:
+//	      baseUrl: "https://*.atlassian.net"
+//	    debug:
+//	      enabled: true
+//	      expires: 24h
+//	  - select:
+//	      clientKey: "1234-*"
+//	    unlicensed:
+//	      allowed: true
+//	      expires: 30d
+//	      reason: sales evaluation extension
+//	    set:
+//	      custom-key: value
+//	    unset:
+//	      - old-key
+type ChangeFile struct {
+	Changes []*Change `json:"changes" yaml:"changes"`
+}
+
+type Change struct {
+	Select     TenantSelector         `json:"select" yaml:"select"`
+	Debug      *DebugPatch            `json:"debug,omitempty" yaml:"debug,omitempty"`
+	Unlicensed *UnlicensedPatch       `json:"unlicensed,omitempty" yaml:"unlicensed,omitempty"`
+	Flag       *FlagPatch             `json:"flag,omitempty" yaml:"flag,omitempty"`
+	Set        map[string]interface{} `json:"set,omitempty" yaml:"set,omitempty"`
+	Unset      []string               `json:"unset,omitempty" yaml:"unset,omitempty"`
+
+	debugExpiry      time.Duration
+	unlicensedExpiry time.Duration
+}
+
+// TenantSelector matches tenants by glob patterns, where "*" matches any
+// sequence of characters and "?" any single character; all given patterns
+// must match and an empty selector matches nothing
+type TenantSelector struct {
+	ClientKey string `json:"clientKey,omitempty" yaml:"clientKey,omitempty"`
+	BaseURL   string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty"`
+}
+
+type DebugPatch struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Expires string `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+type UnlicensedPatch struct {
+	Allowed bool   `json:"allowed" yaml:"allowed"`
+	Expires string `json:"expires,omitempty" yaml:"expires,omitempty"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// FlagPatch sets or clears the operator flag, which does not refuse requests,
+// see TenantContext.Flagged
+type FlagPatch struct {
+	Flagged bool   `json:"flagged" yaml:"flagged"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+func ReadChangeFile(path string) (cf *ChangeFile, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	cf = &ChangeFile{}
+	// yaml is a superset of json, unknown fields are refused like the admin api
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(cf); err != nil && !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("error parsing change file %v: %v", path, err)
+		return
+	}
+	if err = cf.Validate(); err != nil {
+		err = fmt.Errorf("invalid change file %v: %v", path, err)
+	}
+	return
+}
+
+func (cf *ChangeFile) Validate() (err error) {
+	if len(cf.Changes) == 0 {
+		return errors.New("no changes given")
+	}
+	for idx, change := range cf.Changes {
+		if change == nil || change.IsEmpty() {
+			return fmt.Errorf("change #%d: no debug, unlicensed, flag, set or unset given", idx+1)
+		} else if change.Select.ClientKey == "" && change.Select.BaseURL == "" {
+			return fmt.Errorf("change #%d: select requires clientKey and/or baseUrl", idx+1)
+		} else if err = change.parseExpiry(); err != nil {
+			return fmt.Errorf("change #%d: %v", idx+1, err)
+		}
+	}
+	return
+}
+
+// parseExpiry validates and parses the debug and unlicensed expiry values
+func (c *Change) parseExpiry() (err error) {
+	if c.Debug != nil && c.Debug.Enabled && c.Debug.Expires != "" {
+		if c.debugExpiry, err = ParseExpiry(c.Debug.Expires); err != nil {
+			return fmt.Errorf("debug %v", err)
+		}
+	}
+	if c.Unlicensed != nil && c.Unlicensed.Allowed && c.Unlicensed.Expires != "" {
+		if c.unlicensedExpiry, err = ParseExpiry(c.Unlicensed.Expires); err != nil {
+			return fmt.Errorf("unlicensed %v", err)
+		}
+	}
+	return
+}
+
+func (s TenantSelector) Match(tenant *store.Tenant) (ok bool) {
+	if s.ClientKey == "" && s.BaseURL == "" {
+		return false
+	}
+	ok = (s.ClientKey == "" || globMatch(s.ClientKey, tenant.ClientKey)) &&
+		(s.BaseURL == "" || globMatch(s.BaseURL, tenant.BaseURL))
+	return
+}
+
+// IsEmpty reports if the change has nothing to apply
+func (c *Change) IsEmpty() bool {
+	return c.Debug == nil && c.Unlicensed == nil && c.Flag == nil && len(c.Set) == 0 && len(c.Unset) == 0
+}
+
+// Apply makes the change to the given context using the same TenantContext
+// methods as the TenantsPanel actions
+func (c *Change) Apply(ctx TenantContext) {
+	if c.Debug != nil {
+		if c.Debug.Enabled {
+			ctx.EnableDebug(c.debugExpiry)
+		} else {
+			ctx.DisableDebug()
+		}
+	}
+	if c.Unlicensed != nil {
+		if c.Unlicensed.Allowed {
+			ctx.AllowUnlicensed(c.unlicensedExpiry, strings.TrimSpace(c.Unlicensed.Reason))
+		} else {
+			ctx.RejectUnlicensed()
+		}
+	}
+	if c.Flag != nil {
+		if c.Flag.Flagged {
+			ctx.Flag(strings.TrimSpace(c.Flag.Reason))
+		} else {
+			ctx.Unflag()
+		}
+	}
+	for k, v := range c.Set {
+		ctx[k] = v
+	}
+	for _, k := range c.Unset {
+		delete(ctx, k)
+	}
+}
+
+// ApplyChangeFile applies all matching changes, in order, to each tenant
+// within a single transaction
+func (f *CConsole) ApplyChangeFile(cf *ChangeFile, dryRun bool) (result *BatchResult, err error) {
+	var tenants, matched []*store.Tenant
+	if tenants, err = f.currentRepo().List(TenantQuery{}); err != nil {
+		return
+	}
+	for _, tenant := range tenants {
+		for _, change := range cf.Changes {
+			if change.Select.Match(tenant) {
+				matched = append(matched, tenant)
+				break
+			}
+		}
+	}
+	result, err = f.BatchUpdate(matched, func(tenant *store.Tenant, ctx TenantContext) {
+		for _, change := range cf.Changes {
+			if change.Select.Match(tenant) {
+				change.Apply(ctx)
+			}
+		}
+	}, dryRun)
+	return
+}
+
+func globMatch(pattern, value string) (ok bool) {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	expr = strings.ReplaceAll(expr, `\?`, `.`)
+	ok, _ = regexp.MatchString("^"+expr+"$", value)
+	return
+}
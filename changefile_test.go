@@ -0,0 +1,82 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern, value string
+		expected       bool
+	}{
+		{"https://*.atlassian.net", "https://alpha.atlassian.net", true},
+		{"https://*.atlassian.net", "https://alpha.example.com", false},
+		{"https://*.atlassian.net", "http://alpha.atlassian.net", false},
+		{"1234-*", "1234-abcd", true},
+		{"1234-*", "x1234-abcd", false},
+		{"client-?", "client-1", true},
+		{"client-?", "client-12", false},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{"(x)+", "(x)+", true},
+		{"*", "", true},
+		{"", "", true},
+		{"", "value", false},
+	} {
+		if ok := globMatch(test.pattern, test.value); ok != test.expected {
+			t.Errorf("%q %q: expected %v, found %v", test.pattern, test.value, test.expected, ok)
+		}
+	}
+}
+
+func TestReadChangeFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name    string
+		content string
+		invalid bool
+	}{
+		{name: "valid.yaml", content: "changes:\n  - select:\n      baseUrl: \"https://*.atlassian.net\"\n    debug:\n      enabled: true\n      expires: 24h\n"},
+		{name: "valid.json", content: `{"changes": [{"select": {"clientKey": "1234-*"}, "unset": ["old-key"]}]}`},
+		{name: "unknown-field.yaml", content: "changes:\n  - select:\n      clientKey: \"1234-*\"\n    debgu:\n      enabled: true\n", invalid: true},
+		{name: "unknown-top.json", content: `{"change": [{"select": {"clientKey": "1234-*"}, "unset": ["old-key"]}]}`, invalid: true},
+		{name: "no-changes.yaml", content: "changes: []\n", invalid: true},
+		{name: "blank.yaml", content: "", invalid: true},
+		{name: "empty-change.yaml", content: "changes:\n  - select:\n      clientKey: \"1234-*\"\n", invalid: true},
+		{name: "no-select.yaml", content: "changes:\n  - unset: [old-key]\n", invalid: true},
+		{name: "bad-expiry.yaml", content: "changes:\n  - select:\n      clientKey: \"1234-*\"\n    debug:\n      enabled: true\n      expires: soon\n", invalid: true},
+	} {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cf, err := ReadChangeFile(path)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.name)
+			}
+		} else if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if len(cf.Changes) != 1 {
+			t.Errorf("%v: expected one change, found %d", test.name, len(cf.Changes))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-curses/cdk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/globals"
+	"github.com/go-enjin/be/pkg/log"
+)
+
+type CopyTarget string
+
+const (
+	CopyBaseURL   CopyTarget = "Base URL"
+	CopyClientKey CopyTarget = "Client Key"
+	CopyContext   CopyTarget = "Context JSON"
+	CopyRecord    CopyTarget = "Record JSON"
+)
+
+var CopyTargets = []CopyTarget{
+	CopyBaseURL,
+	CopyClientKey,
+	CopyContext,
+	CopyRecord,
+}
+
+// TenantCopyText returns the text to copy for the given tenants, one line per
+// tenant for the BaseURL and ClientKey and JSON keyed by BaseURL for multiple
+// contexts; records are exported without the SharedSecret
+func TenantCopyText(tenants []*store.Tenant, target CopyTarget) (text string, err error) {
+	switch target {
+	case CopyBaseURL, CopyClientKey:
+		var lines []string
+		for _, tenant := range tenants {
+			if target == CopyBaseURL {
+				lines = append(lines, tenant.BaseURL)
+			} else {
+				lines = append(lines, tenant.ClientKey)
+			}
+		}
+		text = strings.Join(lines, "\n")
+
+	case CopyContext:
+		contexts := make(map[string]TenantContext)
+		for _, tenant := range tenants {
+			if contexts[tenant.BaseURL], err = ParseTenantContext(tenant); err != nil {
+				return "", fmt.Errorf("%v - %v", tenant.BaseURL, err)
+			}
+		}
+		var data []byte
+		if len(tenants) == 1 {
+			data, err = json.MarshalIndent(contexts[tenants[0].BaseURL], "", "  ")
+		} else {
+			data, err = json.MarshalIndent(contexts, "", "  ")
+		}
+		text = string(data)
+
+	case CopyRecord:
+		if len(tenants) == 1 {
+			var data []byte
+			data, err = json.MarshalIndent(NewExportRecord(tenants[0]), "", "  ")
+			text = string(data)
+			return
+		}
+		var buf bytes.Buffer
+		if err = ExportTenants(&buf, tenants); err == nil {
+			text = strings.TrimSpace(buf.String())
+		}
+
+	default:
+		err = fmt.Errorf("unknown copy target: %v", target)
+	}
+	return
+}
+
+// CopyFilePath returns a new timestamped file path within the system temp
+// directory, for terminals without a usable clipboard
+func CopyFilePath() (path string) {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("%v-copy-%v.txt", globals.BinName, time.Now().Format("20060102-150405.000")))
+}
+
+// CopyToFile writes the text to the file, replacing any previous copy
+func CopyToFile(path, text string) (err error) {
+	err = os.WriteFile(path, []byte(text+"\n"), 0600)
+	return
+}
+
+// copyText sends the text to the terminal clipboard using an OSC52 escape
+// sequence, which also works over SSH; without a terminal the text is written
+// to a temp file instead, the same file for every copy of the session
+func (c *CCurses) copyText(what, text string) {
+	if screen := c.console.Display().Screen(); screen != nil {
+		if _, offscreen := screen.(cdk.OffScreen); !offscreen {
+			screen.CopyToClipboard(text)
+			c.notify("Copy", fmt.Sprintf("%v sent to the terminal clipboard", what))
+			return
+		}
+	}
+	if c.copyFile == "" {
+		c.copyFile = CopyFilePath()
+	}
+	if err := CopyToFile(c.copyFile, text); err != nil {
+		log.ErrorF("error writing copy file: %v", err)
+		c.notify("Copy Failed", fmt.Sprintf("error writing copy file: %v", err))
+		return
+	}
+	c.notify("Copy", fmt.Sprintf("%v written to:\n%v", what, c.copyFile))
+}
+
+// promptCopy asks which field of the tenants to copy
+func (c *CCurses) promptCopy(tenants []*store.Tenant) {
+	if len(tenants) == 0 {
+		return
+	}
+	var options []string
+	for _, target := range CopyTargets {
+		options = append(options, string(target))
+	}
+	message := fmt.Sprintf("Copy from %v:", tenants[0].BaseURL)
+	if len(tenants) > 1 {
+		message = fmt.Sprintf("Copy from %d selected tenants:", len(tenants))
+	}
+	c.promptChoice("Copy", message, options, func(idx int) {
+		if text, err := TenantCopyText(tenants, CopyTargets[idx]); err != nil {
+			c.notify("Copy Failed", err.Error())
+		} else {
+			c.copyText(string(CopyTargets[idx]), text)
+		}
+	})
+}
@@ -0,0 +1,313 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-enjin/be/pkg/feature"
+	"github.com/go-enjin/be/pkg/globals"
+
+	gonnectian "github.com/go-enjin/features-gonnectian"
+)
+
+func (f *CConsole) makeCommand() (command *cli.Command) {
+	name := f.Tag().Kebab()
+	command = &cli.Command{
+		Name:      name,
+		Usage:     "gonnectian tenant maintenance",
+		UsageText: globals.BinName + " [global options] " + name + " <command>",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "sweep",
+				Usage:       "disable expired time-limited tenant settings",
+				UsageText:   globals.BinName + " " + name + " sweep [--dry-run]",
+				Description: "Disables any tenant debug flags and revokes any unlicensed grants which have passed their expiry time, within all tenant sources.",
+				Action:      f.sweepAction,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "report expired settings without changing anything",
+					},
+				},
+			},
+			{
+				Name:        "apply",
+				Usage:       "apply a YAML or JSON tenant change file",
+				UsageText:   globals.BinName + " " + name + " apply [--dry-run] [--source <label>] <file>",
+				Description: "Applies the context changes described in the given file to all tenants matching the selectors.",
+				Action:      f.applyAction,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show the per-tenant changes without saving anything",
+					},
+					&cli.StringFlag{
+						Name:  "source",
+						Usage: "label of the tenant source to change, defaults to the first source",
+					},
+				},
+			},
+			{
+				Name:        "migrate",
+				Usage:       "create or update the context history table of each tenant source",
+				UsageText:   globals.BinName + " " + name + " migrate",
+				Description: "Creates the table recording the context versions of the tenants of each source, or updates it for this version of the console. The tenant tables of features-gonnectian are never changed. Context history is disabled for any source without this table, no versions are recorded for it by the console, the apply command or the admin API. A roll back to a recorded version restores only the debug, unlicensed and flag settings, the license and any other keys maintained by features-gonnectian are kept as they are.",
+				Action:      f.migrateAction,
+			},
+			{
+				Name:        "record-apps",
+				Usage:       "record the app installed by each tenant without one",
+				UsageText:   globals.BinName + " " + name + " record-apps [--dry-run]",
+				Description: "Records the descriptor key and baseUrl of the app installed within the context of each tenant of all tenant sources without them, the app is the one of the gonnectian features which has a tenant with the same base url and client key. Tenants found by more than one app are skipped, as are tenants found by none.",
+				Action:      f.recordAppsAction,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "report the apps found without changing anything",
+					},
+				},
+			},
+			{
+				Name:        "metrics",
+				Usage:       "print tenant metrics in the Prometheus text format",
+				UsageText:   globals.BinName + " " + name + " metrics",
+				Description: "Prints the tenant counts of all tenant sources by installed state, product type, license, debug and unlicensed settings and operator flags, along with the number of tenants with an invalid context. The same metrics are served at /metrics by the admin API.",
+				Action:      f.metricsAction,
+			},
+			{
+				Name:        "serve",
+				Usage:       "serve the admin API without the console user interface",
+				UsageText:   globals.BinName + " [--gonnectian-api-token <token>] " + name + " serve [--listen <addr>] [--source <label>]",
+				Description: "Serves the gonnectian admin API on a localhost address until interrupted, all requests require the token given with the --" + APITokenFlag.Name + " global flag or the GONNECTIAN_API_TOKEN environment variable.",
+				Action:      f.serveAction,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "localhost address to listen on, defaults to the --" + APIListenFlag.Name + " global flag or " + DefaultAPIListen,
+					},
+					&cli.StringFlag{
+						Name:  "source",
+						Usage: "label of the tenant source to serve, defaults to the first source",
+					},
+				},
+			},
+		},
+	}
+	return
+}
+
+// startupDB is used by command actions to connect to the configured databases
+// without starting the full console, or to the synthetic tenants in demo mode
+func (f *CConsole) startupDB(ctx *cli.Context) (err error) {
+	var dbs []feature.Database
+	if f.demo = ctx.Bool(DemoFlag.Name); !f.demo {
+		dbs = feature.FilterTyped[feature.Database](f.features.List())
+		for _, fdb := range dbs {
+			if err = fdb.Startup(ctx); err != nil {
+				return
+			}
+		}
+	}
+	err = f.prepareSources(func(tag string) (db interface{}, err error) {
+		return findDB(dbs, tag)
+	})
+	if err != nil {
+		return
+	}
+	if label := ctx.String("source"); label != "" {
+		if idx, ok := f.FindSource(label); !ok {
+			err = fmt.Errorf("tenant source not found: %q", label)
+		} else {
+			err = f.SelectSource(idx)
+		}
+	}
+	return
+}
+
+// findDB returns the database with the given tag from the first of the db
+// features which has it
+func findDB(dbs []feature.Database, tag string) (db interface{}, err error) {
+	for _, fdb := range dbs {
+		if db, err = fdb.DB(tag); err == nil {
+			return
+		}
+	}
+	err = fmt.Errorf("db feature not found by tag: %v", tag)
+	return
+}
+
+func (f *CConsole) sweepAction(ctx *cli.Context) (err error) {
+	if err = f.startupDB(ctx); err != nil {
+		return
+	}
+	var swept map[string][]string
+	dryRun := ctx.Bool("dry-run")
+	if swept, err = f.SweepExpired(dryRun); err != nil {
+		return
+	}
+	writeSwept(os.Stdout, swept, dryRun)
+	return
+}
+
+// writeSwept prints the changes of a sweep sorted by tenant followed by the
+// number of tenants changed, or which would be changed by a dry run
+func writeSwept(w io.Writer, swept map[string][]string, dryRun bool) {
+	var urls []string
+	for url := range swept {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		for _, change := range swept[url] {
+			_, _ = fmt.Fprintf(w, "%v: %v\n", url, change)
+		}
+	}
+	if dryRun {
+		_, _ = fmt.Fprintf(w, "# %d tenants have expired settings\n", len(urls))
+	} else {
+		_, _ = fmt.Fprintf(w, "# %d tenants updated\n", len(urls))
+	}
+}
+
+func (f *CConsole) migrateAction(ctx *cli.Context) (err error) {
+	if err = f.startupDB(ctx); err != nil {
+		return
+	}
+	for _, src := range f.sources {
+		// the demo mode tenants are not stored in a database
+		if repo, ok := src.repo.(*GormTenantRepository); ok {
+			if err = repo.MigrateHistory(); err != nil {
+				return fmt.Errorf("%v: %v", src.Label, err)
+			}
+			fmt.Printf("%v: context history table %v is ready\n", src.Label, repo.historyTable())
+		}
+	}
+	return
+}
+
+func (f *CConsole) recordAppsAction(ctx *cli.Context) (err error) {
+	if err = f.startupDB(ctx); err != nil {
+		return
+	} else if f.demo {
+		return fmt.Errorf("the record-apps command does not apply to the demo mode tenants")
+	}
+	// the descriptor key and baseUrl are only final once started
+	for _, gf := range feature.FilterTyped[gonnectian.Feature](f.features.List()) {
+		if err = gf.Startup(ctx); err != nil {
+			return
+		}
+	}
+	var recorded, skipped map[string]string
+	dryRun := ctx.Bool("dry-run")
+	if recorded, skipped, err = f.RecordTenantApps(dryRun); err != nil {
+		return
+	}
+	writeRecorded(os.Stdout, recorded, skipped, dryRun)
+	return
+}
+
+// writeRecorded prints the apps recorded and the tenants skipped, each sorted
+// by tenant, followed by the number of tenants updated, or which would be
+// updated by a dry run
+func writeRecorded(w io.Writer, recorded, skipped map[string]string, dryRun bool) {
+	for _, results := range []map[string]string{recorded, skipped} {
+		var urls []string
+		for url := range results {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+		for _, url := range urls {
+			_, _ = fmt.Fprintf(w, "%v: %v\n", url, results[url])
+		}
+	}
+	if dryRun {
+		_, _ = fmt.Fprintf(w, "# %d tenants would be updated, %d skipped\n", len(recorded), len(skipped))
+	} else {
+		_, _ = fmt.Fprintf(w, "# %d tenants updated, %d skipped\n", len(recorded), len(skipped))
+	}
+}
+
+func (f *CConsole) metricsAction(ctx *cli.Context) (err error) {
+	if err = f.startupDB(ctx); err != nil {
+		return
+	}
+	var metrics []*TenantMetrics
+	if metrics, err = f.CollectMetrics(); err != nil {
+		return
+	}
+	err = WriteMetrics(os.Stdout, metrics)
+	return
+}
+
+func (f *CConsole) serveAction(ctx *cli.Context) (err error) {
+	listen := ctx.String("listen")
+	if listen == "" {
+		if listen = ctx.String(APIListenFlag.Name); listen == "" {
+			listen = DefaultAPIListen
+		}
+	}
+	var api *AdminAPI
+	if api, err = NewAdminAPI(f, ctx.String(APITokenFlag.Name)); err != nil {
+		return
+	} else if err = f.startupDB(ctx); err != nil {
+		return
+	} else if err = api.Start(listen); err != nil {
+		return
+	}
+	defer api.Stop()
+	signals, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	return
+}
+
+func (f *CConsole) applyAction(ctx *cli.Context) (err error) {
+	if ctx.NArg() != 1 {
+		return cli.ShowSubcommandHelp(ctx)
+	}
+	var cf *ChangeFile
+	if cf, err = ReadChangeFile(ctx.Args().First()); err != nil {
+		return
+	} else if err = f.startupDB(ctx); err != nil {
+		return
+	}
+	var result *BatchResult
+	dryRun := ctx.Bool("dry-run")
+	if result, err = f.ApplyChangeFile(cf, dryRun); err != nil {
+		return
+	}
+	for _, key := range result.Updated {
+		fmt.Printf("%v (%v)\n", result.BaseURLs[key], key)
+		for _, line := range result.Diffs[key] {
+			fmt.Printf("  %v\n", line)
+		}
+	}
+	if dryRun {
+		fmt.Printf("# dry-run: %d tenants would be updated, %d unchanged\n", len(result.Updated), len(result.Unchanged))
+	} else {
+		fmt.Printf("# %v\n", result.Summary())
+	}
+	return
+}
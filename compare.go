@@ -0,0 +1,114 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+const compareUnset = "(unset)"
+
+// CompareRow is a single field of a tenant comparison, context keys are
+// prefixed with "context."
+type CompareRow struct {
+	Key   string
+	Left  string
+	Right string
+}
+
+func (r CompareRow) Differs() bool {
+	return r.Left != r.Right
+}
+
+// CompareTenants returns a key-by-key comparison of the non-secret fields and
+// the contexts of the two tenants with times in the given location, the
+// SharedSecret is deliberately omitted
+func CompareTenants(left, right *store.Tenant, loc *time.Location) (rows []CompareRow, err error) {
+	fields := func(tenant *store.Tenant) []string {
+		return []string{
+			tenant.ClientKey,
+			tenant.BaseURL,
+			tenant.ProductType,
+			tenant.Description,
+			tenant.OauthClientId,
+			tenant.PublicKey,
+			fmt.Sprintf("%v", tenant.AddonInstalled),
+			tenant.CreatedAt.In(loc).Format(TimeFormat),
+			tenant.UpdatedAt.In(loc).Format(TimeFormat),
+		}
+	}
+	names := []string{"clientKey", "baseUrl", "productType", "description", "oauthClientId", "publicKey", "addonInstalled", "createdAt", "updatedAt"}
+	lf, rf := fields(left), fields(right)
+	for idx, name := range names {
+		rows = append(rows, CompareRow{Key: name, Left: lf[idx], Right: rf[idx]})
+	}
+
+	var lc, rc TenantContext
+	if lc, err = ParseTenantContext(left); err != nil {
+		return nil, fmt.Errorf("%v - %v", left.BaseURL, err)
+	} else if rc, err = ParseTenantContext(right); err != nil {
+		return nil, fmt.Errorf("%v - %v", right.BaseURL, err)
+	}
+	for _, k := range contextKeys(lc, rc) {
+		row := CompareRow{Key: "context." + k, Left: compareUnset, Right: compareUnset}
+		if v, ok := lc[k]; ok {
+			row.Left = formatValue(v)
+		}
+		if v, ok := rc[k]; ok {
+			row.Right = formatValue(v)
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// FormatComparison renders the rows side-by-side within the given width,
+// marking the rows which differ with a "≠"
+func FormatComparison(left, right *store.Tenant, rows []CompareRow, width int) (text string) {
+	keyWidth := 0
+	for _, row := range rows {
+		if size := utf8.RuneCountInString(row.Key); size > keyWidth {
+			keyWidth = size
+		}
+	}
+	valueWidth := (width - keyWidth - 2 - 2 - 2) / 2
+	if valueWidth < tableMinURLWidth {
+		valueWidth = tableMinURLWidth
+	}
+
+	format := func(marker, key, l, r string) string {
+		return strings.TrimRight(marker+" "+padCell(key, keyWidth)+"  "+padCell(l, valueWidth)+"  "+padCell(r, valueWidth), " ")
+	}
+	lines := []string{format(" ", "", left.BaseURL, right.BaseURL)}
+	differ := 0
+	for _, row := range rows {
+		marker := " "
+		if row.Differs() {
+			marker = "≠"
+			differ += 1
+		}
+		lines = append(lines, format(marker, row.Key, row.Left, row.Right))
+	}
+	lines = append(lines, "", fmt.Sprintf("%d of %d fields differ", differ, len(rows)))
+	text = strings.Join(lines, "\n")
+	return
+}
@@ -19,23 +19,26 @@ package gonnectian
 import (
 	"fmt"
 	"sync"
-
-	"gorm.io/gorm"
+	"unicode/utf8"
 
 	"github.com/go-curses/cdk"
 	cenums "github.com/go-curses/cdk/lib/enums"
 	"github.com/go-curses/cdk/lib/paint"
 	"github.com/go-curses/ctk"
 	"github.com/go-curses/ctk/lib/enums"
+
+	"github.com/go-enjin/be/pkg/log"
 )
 
-var gKnownPanels []Panel
+// gKnownPanels are the constructors of the panels, each CCurses makes its own
+// instances
+var gKnownPanels []func() Panel
 var ButtonActiveTheme paint.ThemeName = "toggle-button-active"
 
 func init() {
 	gKnownPanels = append(gKnownPanels,
-		&AppInfoPanel{},
-		&TenantsPanel{},
+		func() Panel { return &AppInfoPanel{} },
+		func() Panel { return &TenantsPanel{} },
 	)
 
 	borders, _ := paint.GetDefaultBorderRunes(paint.StockBorder)
@@ -73,7 +76,6 @@ func init() {
 }
 
 type CCurses struct {
-	db      *gorm.DB
 	console *CConsole
 
 	pOrder []string
@@ -84,23 +86,30 @@ type CCurses struct {
 	panelArea  ctk.VBox
 	toggleArea ctk.HButtonBox
 	toggles    map[string]ctk.Button
+	sourceBtn  ctk.Button
 
 	defaultToggleTheme paint.Theme
 	activeToggleTheme  paint.Theme
+	theme              *ConsoleTheme
+	bindings           []KeyBinding
+	commands           []Command
+	// dialogs are the dialogs shown by runDialog which have no response yet
+	dialogs []ctk.Dialog
+	// copyFile is where copies are written without a terminal clipboard
+	copyFile string
 
 	sync.RWMutex
 }
 
 func NewCurses(console *CConsole) (c *CCurses, err error) {
 	c = &CCurses{
-		db:      console.db,
 		window:  console.Window(),
 		console: console,
 		toggles: make(map[string]ctk.Button),
 		panels:  make(map[string]Panel),
 	}
 	c.defaultToggleTheme, _ = paint.GetTheme(ctk.ButtonColorTheme)
-	c.activeToggleTheme, _ = paint.GetTheme("toggle-button-active")
+	c.activeToggleTheme, _ = paint.GetTheme(ButtonActiveTheme)
 
 	vbox := c.window.GetVBox()
 
@@ -114,14 +123,15 @@ func NewCurses(console *CConsole) (c *CCurses, err error) {
 
 	// accelMap := ctk.NewAccelerator("/quit")
 
-	for idx, panel := range gKnownPanels {
+	for idx, newPanel := range gKnownPanels {
+		panel := newPanel()
 		if err = panel.Init(c); err != nil {
 			err = fmt.Errorf("error init %v panel: %v", panel.Key(), err)
 			return
 		}
 		c.panels[panel.Key()] = panel
 		c.pOrder = append(c.pOrder, panel.Key())
-		toggle := c.makePanelToggle(idx+1, panel)
+		toggle := c.makePanelToggle(idx+1, panel, idx == 0)
 		c.toggles[panel.Key()] = toggle
 		c.panelArea.PackStart(panel.Container(), true, true, 0)
 		c.toggleArea.PackStart(toggle, false, false, 0)
@@ -133,7 +143,18 @@ func NewCurses(console *CConsole) (c *CCurses, err error) {
 		}
 	}
 
-	b := c.makeToggleButton("quit", "Quit <F10>", cdk.KeyF10, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(console.sources) > 1 {
+		c.sourceBtn = c.makeToggleButton("source", c.sourceLabel(), "Select the tenant source", cdk.KeyF9, c.selectSourceHandler)
+		c.sourceBtn.SetTheme(c.defaultToggleTheme)
+		c.toggleArea.PackStart(c.sourceBtn, false, false, 0)
+	}
+
+	tb := c.makeToggleButton("theme", "Theme <F8>", "Select the console theme", cdk.KeyF8, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		c.themeHandler()
+		return cenums.EVENT_STOP
+	})
+	c.toggleArea.SetChildSecondary(tb, true)
+	b := c.makeToggleButton("quit", "Quit <F10>", "Quit the console", cdk.KeyF10, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
 		c.console.Display().RequestQuit()
 		return cenums.EVENT_STOP
 	})
@@ -143,19 +164,49 @@ func NewCurses(console *CConsole) (c *CCurses, err error) {
 	sep.SetSizeRequest(-1, 1)
 	c.toggleArea.SetChildSecondary(sep, true)
 	c.toggleArea.SetChildPacking(sep, true, true, 0, enums.PackStart)
+
+	c.window.Connect(ctk.SignalEventKey, "gonnectian-console-key-handler", c.keyEventHandler)
+	c.window.Connect(ctk.SignalEventMouse, "gonnectian-console-mouse-handler", c.mouseEventHandler)
+	return
+}
+
+// entryFocused returns true if the focus is on a text entry, which receives
+// the runes typed instead of the key bindings
+func (c *CCurses) entryFocused() (focused bool) {
+	if w := c.window.GetFocus(); w != nil {
+		_, focused = w.Self().(ctk.Entry)
+	}
 	return
 }
 
-func (c *CCurses) makePanelToggle(id int, p Panel) (b ctk.Button) {
-	label := fmt.Sprintf("%s <F%d>", p.Name(), id)
+func (c *CCurses) makePanelToggle(id int, p Panel, active bool) (b ctk.Button) {
+	label := c.panelToggleLabel(id, p, active)
 	accelKey := cdk.Key(0)
-	if id > 0 && id < 10 {
-		accelKey = cdk.Key(int16(cdk.KeyF1) + int16(id-1))
+	if n := panelFKey(id); n > 1 && n < 8 {
+		accelKey = cdk.Key(int16(cdk.KeyF1) + int16(n-1))
+	}
+	return c.makeToggleButton(p.Key(), label, "Show the "+p.Name()+" panel", accelKey, c.togglePanelHandler, id, p)
+}
+
+func (c *CCurses) panelToggleLabel(id int, p Panel, active bool) (label string) {
+	label = fmt.Sprintf("%s <F%d>", p.Name(), panelFKey(id))
+	if c.monochrome() {
+		label = MarkToggle(label, active)
 	}
-	return c.makeToggleButton(p.Key(), label, accelKey, c.togglePanelHandler, id, p)
+	return
 }
 
-func (c *CCurses) makeToggleButton(key, label string, accelKey cdk.Key, handler cdk.SignalListenerFn, data ...interface{}) (b ctk.Button) {
+// panelFKey returns the number of the function key toggling the panel with the
+// id given, F1 is reserved for the help overlay and F8 to F10 for the theme,
+// source and quit buttons
+func panelFKey(id int) (n int) {
+	return id + 1
+}
+
+// makeToggleButton returns a new button for the toggle area, the accelKey is
+// listed in the help overlay and the button in the command palette with the
+// help text given
+func (c *CCurses) makeToggleButton(key, label, help string, accelKey cdk.Key, handler cdk.SignalListenerFn, data ...interface{}) (b ctk.Button) {
 	b = ctk.NewButtonWithLabel(label)
 	b.Show()
 	b.SetSizeRequest(-1, 1)
@@ -168,10 +219,52 @@ func (c *CCurses) makeToggleButton(key, label string, accelKey cdk.Key, handler
 			return
 		})
 		c.console.Window().AddAccelGroup(accelGroup)
+		c.addKeyBinding(accelKey, help)
 	}
+	c.addCommand(Command{Name: help, Keys: cdk.KeyNames[accelKey], Run: func() {
+		b.GrabFocus()
+		b.Activate()
+	}})
 	return
 }
 
+// KeyHandlerPanel is implemented by panels which handle key presses, such as
+// single letter shortcuts, while they are the active panel
+type KeyHandlerPanel interface {
+	HandleKey(evt *cdk.EventKey) (handled bool)
+}
+
+// isCtrlKey reports if the event is the given control key, cdk delivers these
+// as the lowercase letter with the control modifier
+func isCtrlKey(evt *cdk.EventKey, ctrlKey cdk.Key) bool {
+	if evt.Key() == ctrlKey {
+		return true
+	}
+	key, _, _ := cdk.DecodeCtrlKey(ctrlKey)
+	return evt.Key() == key && evt.Modifiers().Has(cdk.ModCtrl)
+}
+
+func (c *CCurses) keyEventHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	evt, ok := argv[1].(*cdk.EventKey)
+	if !ok {
+		return cenums.EVENT_PASS
+	}
+	if p, ok := c.panels[c.active].(KeyHandlerPanel); ok && p.HandleKey(evt) {
+		return cenums.EVENT_STOP
+	}
+	if evt.Key() == cdk.KeyF1 || evt.Key() == cdk.KeyRune && evt.Rune() == '?' && !c.entryFocused() {
+		c.showHelp()
+		return cenums.EVENT_STOP
+	} else if isCtrlKey(evt, cdk.KeyCtrlP) {
+		c.showPalette()
+		return cenums.EVENT_STOP
+	}
+	return cenums.EVENT_PASS
+}
+
 func (c *CCurses) togglePanelHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
 	if p, ok := data[1].(Panel); ok {
 		c.active = p.Key()
@@ -180,6 +273,32 @@ func (c *CCurses) togglePanelHandler(data []interface{}, argv ...interface{}) ce
 	return cenums.EVENT_PASS
 }
 
+func (c *CCurses) sourceLabel() (label string) {
+	return fmt.Sprintf("Source: %v <F9>", c.console.Source().Label)
+}
+
+func (c *CCurses) selectSourceHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	var labels []string
+	for _, src := range c.console.Sources() {
+		labels = append(labels, src.Label)
+	}
+	c.promptChoice("Tenant Source", "Select the tenant table to manage:", labels, func(idx int) {
+		if err := c.console.SelectSource(idx); err != nil {
+			log.ErrorF("%v", err)
+			return
+		}
+		for _, panel := range c.panels {
+			if p, ok := panel.(SourceChangedPanel); ok {
+				p.SourceChanged()
+			}
+		}
+		c.sourceBtn.SetLabel(c.sourceLabel())
+		c.window.SetTitle(c.console.Title())
+		c.Refresh()
+	})
+	return cenums.EVENT_STOP
+}
+
 func (c *CCurses) Refresh() {
 	p, _ := c.panels[c.active]
 	c.window.Freeze()
@@ -192,6 +311,17 @@ func (c *CCurses) Refresh() {
 			}
 		}
 	}
+	if c.monochrome() {
+		// mark the active toggle with text as well as attributes
+		for idx, key := range c.pOrder {
+			if b, ok := c.toggles[key]; ok {
+				label := c.panelToggleLabel(idx+1, c.panels[key], key == c.active)
+				b.SetLabel(label)
+				// keep the markers within the button bookends
+				b.SetSizeRequest(utf8.RuneCountInString(label)+2, 1)
+			}
+		}
+	}
 	if b, ok := c.toggles[c.active]; ok {
 		b.SetTheme(c.activeToggleTheme)
 		b.GrabFocus()
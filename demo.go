@@ -0,0 +1,140 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	gonnectian "github.com/go-enjin/features-gonnectian"
+)
+
+var (
+	// DemoTenantCount is the number of synthetic tenants used in demo mode
+	DemoTenantCount = 42
+	// DemoSeed makes the synthetic tenants the same on every run, for
+	// consistent screenshots and recordings, apart from their dates and
+	// expiries which stay relative to the time of the run
+	DemoSeed int64 = 20230601
+)
+
+var (
+	demoSites = []string{
+		"acme", "globex", "initech", "umbrella", "hooli", "vandelay", "wonka",
+		"stark", "wayne", "cyberdyne", "soylent", "tyrell", "monarch", "oscorp",
+		"aperture", "blackmesa", "gringotts", "dunder", "pied-piper", "nakatomi",
+	}
+	demoProducts = []string{"jira", "jira", "jira", "confluence", "confluence"}
+	demoLicenses = []string{"active", "active", "active", "none", "expired"}
+	demoReasons  = []string{
+		"sales evaluation extension",
+		"partner sandbox",
+		"renewal in progress",
+	}
+	demoFlagReasons = []string{
+		"abuse",
+		"chargeback",
+		"security review",
+	}
+)
+
+// DemoApps returns synthetic app descriptors used in demo mode
+func DemoApps() (apps []AppDescriptor) {
+	for _, info := range []struct{ name, key, version, url string }{
+		{"Demo Reports", "io.example.demo-reports", "1.4.2", "https://apps.example.io/reports/v1"},
+		{"Demo Reports", "io.example.demo-reports", "2.0.0", "https://apps.example.io/reports/v2"},
+		{"Demo Timesheets", "io.example.demo-timesheets", "3.1.0", "https://apps.example.io/timesheets"},
+	} {
+		d := gonnectian.NewDescriptor()
+		d.Name = info.name
+		d.Key = info.key
+		d.Version = info.version
+		d.BaseURL = info.url
+		d.Licensing = true
+		apps = append(apps, AppDescriptor{
+			URL:        info.url + "/atlassian-connect.json",
+			Descriptor: d,
+		})
+	}
+	return
+}
+
+// DemoTenants returns count synthetic tenants with a varied mix of products,
+// licenses, install states, dates and context settings, the same seed always
+// produces the same tenants with dates relative to the current time so that
+// expiries remain in the future
+func DemoTenants(count int, seed int64) (tenants []*store.Tenant) {
+	rng := rand.New(rand.NewSource(seed))
+	now := time.Now().UTC().Truncate(time.Minute)
+	apps := DemoApps()
+	pick := func(list []string) string {
+		return list[rng.Intn(len(list))]
+	}
+
+	for idx := 0; idx < count; idx++ {
+		site := demoSites[idx%len(demoSites)]
+		if idx >= len(demoSites) {
+			site = fmt.Sprintf("%v-%d", site, idx/len(demoSites)+1)
+		}
+
+		created := now.Add(-time.Duration(rng.Intn(720)+1) * 24 * time.Hour)
+		updated := created.Add(time.Duration(rng.Int63n(int64(now.Sub(created)))))
+
+		ctx := TenantContext{
+			CtxKeyDebug:   "false",
+			CtxKeyLicense: pick(demoLicenses),
+		}
+		switch roll := rng.Intn(10); {
+		case roll == 0:
+			ctx.Flag(pick(demoFlagReasons))
+		case roll < 3:
+			ctx.EnableDebug(time.Duration(rng.Intn(48)+1) * time.Hour)
+		}
+		// most tenants have the app recorded, as by the record-apps command
+		if roll := rng.Intn(len(apps) + 1); roll < len(apps) {
+			ctx[CtxKeyAppKey] = apps[roll].Descriptor.Key
+			ctx[CtxKeyAppBaseURL] = apps[roll].Descriptor.BaseURL
+		}
+		if ctx.License() != "active" && !ctx.Flagged() {
+			if rng.Intn(2) == 0 {
+				ctx.AllowUnlicensed(time.Duration(rng.Intn(30)+1)*24*time.Hour, pick(demoReasons))
+			} else {
+				// as set by features-gonnectian for unlicensed requests
+				ctx.RejectUnlicensed()
+				ctx[CtxKeyReject] = "unlicensed"
+			}
+		}
+
+		product := pick(demoProducts)
+		tenant := &store.Tenant{
+			ClientKey:      fmt.Sprintf("demo-%08x-%04x", rng.Uint32(), idx),
+			SharedSecret:   fmt.Sprintf("demo-secret-%d", idx),
+			BaseURL:        fmt.Sprintf("https://%v.atlassian.net", site),
+			ProductType:    product,
+			Description:    fmt.Sprintf("Atlassian %v at %v", product, site),
+			AddonInstalled: rng.Intn(5) != 0,
+			CreatedAt:      created,
+			UpdatedAt:      updated,
+		}
+		_ = ctx.Apply(tenant)
+		tenants = append(tenants, tenant)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+// demoSummary returns the fields of the demo tenant which do not depend on
+// the time of the run
+func demoSummary(t *testing.T, idx int, tenant *store.Tenant) (summary string) {
+	t.Helper()
+	ctx, err := ParseTenantContext(tenant)
+	if err != nil {
+		t.Fatalf("%d: %v", idx, err)
+	}
+	summary = fmt.Sprintf("%v %v %v %q %v lic=%v debug=%v flagged=%v unlicensed=%v app=%v",
+		tenant.ClientKey, tenant.BaseURL, tenant.ProductType, tenant.Description, tenant.AddonInstalled,
+		ctx.License(), ctx.Debug(), ctx.FlagReason(), ctx.AllowedUnlicensed(), ctx.AppBaseURL())
+	return
+}
+
+func TestDemoTenants(t *testing.T) {
+	first, second := DemoTenants(DemoTenantCount, DemoSeed), DemoTenants(DemoTenantCount, DemoSeed)
+	if len(first) != DemoTenantCount || len(second) != DemoTenantCount {
+		t.Fatalf("expected %d tenants, found %d and %d", DemoTenantCount, len(first), len(second))
+	}
+	other := DemoTenants(DemoTenantCount, DemoSeed+1)
+
+	clientKeys := make(map[string]bool)
+	differ := false
+	for idx, tenant := range first {
+		summary := demoSummary(t, idx, tenant)
+		if again := demoSummary(t, idx, second[idx]); again != summary {
+			t.Errorf("%d: expected the same tenant for the same seed, found:\n%v\n%v", idx, summary, again)
+		}
+		differ = differ || demoSummary(t, idx, other[idx]) != summary
+
+		if clientKeys[tenant.ClientKey] {
+			t.Errorf("%d: duplicate client key %q", idx, tenant.ClientKey)
+		}
+		clientKeys[tenant.ClientKey] = true
+		site := strings.TrimSuffix(strings.TrimPrefix(tenant.BaseURL, "https://"), ".atlassian.net")
+		if expected := fmt.Sprintf("Atlassian %v at %v", tenant.ProductType, site); tenant.Description != expected {
+			t.Errorf("%d: expected description %q, found %q", idx, expected, tenant.Description)
+		}
+		if tenant.UpdatedAt.Before(tenant.CreatedAt) {
+			t.Errorf("%d: expected updated %v after created %v", idx, tenant.UpdatedAt, tenant.CreatedAt)
+		}
+		ctx, _ := ParseTenantContext(tenant)
+		if _, rejected := ctx.Rejected(); rejected && (ctx.License() == "active" || ctx.AllowedUnlicensed()) {
+			t.Errorf("%d: expected only unlicensed tenants without a grant rejected: %v", idx, ctx)
+		}
+	}
+	if !differ {
+		t.Errorf("expected other tenants for another seed")
+	}
+}
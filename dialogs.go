@@ -0,0 +1,239 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+// runDialog shows the dialog and calls fn with the response once the dialog
+// is gone, so that any dialog opened by fn is presented on top rather than
+// behind the window focused when this one is destroyed
+func (c *CCurses) runDialog(d ctk.Dialog, fn func(response enums.ResponseType)) {
+	c.Lock()
+	c.dialogs = append(c.dialogs, d)
+	c.Unlock()
+	// listeners are called in the reverse order of connection, so this one
+	// runs before the dialog itself records the response
+	d.Connect(ctk.SignalResponse, "gonnectian-console-dialog-response", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		response := enums.ResponseNone
+		if len(argv) == 1 {
+			if value, ok := argv[0].(enums.ResponseType); ok {
+				response = value
+			}
+		}
+		c.forgetDialog(d)
+		if err := c.console.asyncCall(func() {
+			d.Destroy()
+			fn(response)
+			c.console.Display().RequestDraw()
+			c.console.Display().RequestShow()
+		}); err != nil {
+			log.DebugF("dialog response not handled: %v", err)
+		}
+		return cenums.EVENT_PASS
+	})
+	r := d.Run()
+	// the response is handled above, only release the goroutine delivering it
+	cdk.Go(func() { <-r })
+}
+
+func (c *CCurses) forgetDialog(d ctk.Dialog) {
+	c.Lock()
+	defer c.Unlock()
+	for idx, dialog := range c.dialogs {
+		if dialog.ObjectID() == d.ObjectID() {
+			c.dialogs = append(c.dialogs[:idx], c.dialogs[idx+1:]...)
+			return
+		}
+	}
+}
+
+// cancelDialogs responds to every dialog still shown with a cancel, which
+// none of the dialog callbacks act upon
+func (c *CCurses) cancelDialogs() {
+	c.RLock()
+	dialogs := append([]ctk.Dialog{}, c.dialogs...)
+	c.RUnlock()
+	for _, d := range dialogs {
+		d.Response(enums.ResponseCancel)
+	}
+}
+
+// promptChoice presents a menu of options and calls fn with the index of the
+// option selected, fn is not called if the dialog is cancelled
+func (c *CCurses) promptChoice(title, message string, options []string, fn func(idx int)) {
+	var argv []interface{}
+	for idx, option := range options {
+		argv = append(argv, option, enums.ResponseType(idx+1))
+	}
+	d := ctk.NewButtonMenuDialog(title, message, argv...)
+	d.SetTransientFor(c.window)
+	width, height := textSize(message)
+	for _, option := range options {
+		if size := utf8.RuneCountInString(option) + 2; size > width {
+			width = size
+		}
+	}
+	c.fitDialog(d, width, height+len(options))
+	c.runDialog(d, func(response enums.ResponseType) {
+		if idx := int(response) - 1; idx >= 0 && idx < len(options) {
+			fn(idx)
+		}
+	})
+}
+
+// promptText presents a single-line text entry and calls fn with the text
+// entered, fn is not called if the dialog is cancelled
+func (c *CCurses) promptText(title, message, initial string, fn func(text string)) {
+	d := ctk.NewDialogWithButtons(
+		title, c.window,
+		enums.DialogModal,
+		ctk.StockOk, enums.ResponseOk,
+		ctk.StockCancel, enums.ResponseCancel,
+	)
+	d.SetDefaultResponse(enums.ResponseOk)
+	d.SetSizeRequest(50, 9)
+
+	label := ctk.NewLabel(message)
+	label.Show()
+	label.SetJustify(cenums.JUSTIFY_LEFT)
+	label.SetLineWrap(true)
+	label.SetLineWrapMode(cenums.WRAP_WORD)
+	d.GetContentArea().PackStart(label, true, true, 0)
+
+	entry := ctk.NewEntry(initial)
+	entry.Show()
+	entry.SetSingleLineMode(true)
+	entry.SetSizeRequest(44, 1)
+	d.GetContentArea().PackStart(entry, false, false, 0)
+	activateEntryOnEnter(d, entry)
+	entry.Connect(ctk.SignalActivate, "gonnectian-console-prompt-ok", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		d.Response(enums.ResponseOk)
+		return cenums.EVENT_STOP
+	})
+
+	c.runDialog(d, func(response enums.ResponseType) {
+		if response == enums.ResponseOk {
+			fn(entry.GetText())
+		}
+	})
+	entry.GrabFocus()
+	// continue editing any initial text rather than typing ahead of it
+	entry.SetPosition(utf8.RuneCountInString(initial))
+}
+
+// activateEntryOnEnter activates the entry when enter is pressed while it has
+// the focus, a single-line ctk entry does not activate on its own
+func activateEntryOnEnter(d ctk.Dialog, entry ctk.Entry) {
+	d.Connect(ctk.SignalEventKey, "gonnectian-console-entry-enter", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		if len(argv) < 2 || !entry.HasFocus() {
+			return cenums.EVENT_PASS
+		}
+		if evt, ok := argv[1].(*cdk.EventKey); ok && (evt.Rune() == '\n' || evt.Rune() == '\r') {
+			entry.Activate()
+			return cenums.EVENT_STOP
+		}
+		return cenums.EVENT_PASS
+	})
+}
+
+// promptConfirm presents a yes/no question and calls fn only if confirmed
+func (c *CCurses) promptConfirm(title, message string, fn func()) {
+	d := ctk.NewYesNoDialog(title, message, true)
+	d.SetTransientFor(c.window)
+	width, height := textSize(message)
+	c.fitDialog(d, width, height)
+	c.runDialog(d, func(response enums.ResponseType) {
+		if response == enums.ResponseYes {
+			fn()
+		}
+	})
+}
+
+// showText presents preformatted text within a scrollable dialog sized to fit
+// the text, up to the size of the screen
+func (c *CCurses) showText(title, text string) {
+	width, height := textSize(text)
+
+	d := ctk.NewDialogWithButtons(
+		title, c.window,
+		enums.DialogModal,
+		ctk.StockClose, enums.ResponseClose,
+	)
+	d.SetDefaultResponse(enums.ResponseClose)
+	c.fitDialog(d, width, height)
+
+	scroll := ctk.NewScrolledViewport()
+	scroll.Show()
+	scroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyAutomatic)
+	d.GetContentArea().PackStart(scroll, true, true, 0)
+
+	label := ctk.NewLabel(text)
+	label.Show()
+	label.SetJustify(cenums.JUSTIFY_NONE)
+	label.SetLineWrap(false)
+	label.SetLineWrapMode(cenums.WRAP_NONE)
+	label.SetSizeRequest(width, height)
+	scroll.Add(label)
+
+	c.runDialog(d, func(response enums.ResponseType) {})
+}
+
+// notify presents a message with a close button
+func (c *CCurses) notify(title, message string) {
+	d := ctk.NewMessageDialog(title, message)
+	d.SetTransientFor(c.window)
+	width, height := textSize(message)
+	c.fitDialog(d, width, height)
+	c.runDialog(d, func(response enums.ResponseType) {})
+}
+
+// textSize returns the number of columns and lines of the text
+func textSize(text string) (width, height int) {
+	lines := strings.Split(text, "\n")
+	height = len(lines)
+	for _, line := range lines {
+		if size := utf8.RuneCountInString(line); size > width {
+			width = size
+		}
+	}
+	return
+}
+
+// fitDialog sizes the dialog to show content of the given size along with the
+// title and buttons, up to the size of the screen; the ctk message and menu
+// dialogs leave too little room for their content
+func (c *CCurses) fitDialog(d ctk.Dialog, width, height int) {
+	w, h := c.console.Display().Screen().Size()
+	dw, dh := width+4, height+6
+	if dw > w-4 {
+		dw = w - 4
+	}
+	if dh > h-4 {
+		dh = h - 4
+	}
+	d.SetSizeRequest(dw, dh)
+}
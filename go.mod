@@ -5,12 +5,15 @@ go 1.21
 toolchain go1.21.0
 
 require (
+	github.com/creack/pty v1.1.21
 	github.com/go-curses/cdk v0.5.16
 	github.com/go-curses/ctk v0.5.8
 	github.com/go-enjin/be v0.5.6
 	github.com/go-enjin/features-gonnectian v0.5.6
 	github.com/go-enjin/github-com-craftamap-atlas-gonnect v0.5.6
 	github.com/urfave/cli/v2 v2.26.0
+	gopkg.in/yaml.v3 v3.0.1
+	gorm.io/driver/sqlite v1.5.4
 	gorm.io/gorm v1.25.5
 )
 
@@ -40,7 +43,6 @@ require (
 	github.com/blevesearch/zapx/v14 v14.3.10 // indirect
 	github.com/blevesearch/zapx/v15 v15.3.13 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
-	github.com/creack/pty v1.1.21 // indirect
 	github.com/felixge/fgprof v0.9.3 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
@@ -101,9 +103,7 @@ require (
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gorm.io/datatypes v1.2.0 // indirect
 	gorm.io/driver/mysql v1.5.2 // indirect
 	gorm.io/driver/postgres v1.5.4 // indirect
-	gorm.io/driver/sqlite v1.5.4 // indirect
 )
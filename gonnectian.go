@@ -19,6 +19,8 @@ package gonnectian
 import (
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -28,16 +30,25 @@ import (
 	"github.com/go-curses/cdk/log"
 	"github.com/go-curses/ctk"
 
-	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
-
 	"github.com/go-enjin/be/pkg/feature"
 	"github.com/go-enjin/be/pkg/globals"
+
+	gonnectian "github.com/go-enjin/features-gonnectian"
 )
 
 var (
 	_ Console = (*CConsole)(nil)
 )
 
+var (
+	// DemoFlag is the global flag used to run against synthetic tenants
+	DemoFlag = &cli.BoolFlag{
+		Name:  "demo",
+		Usage: "run the gonnectian console with synthetic in-memory tenants, changes are not saved",
+	}
+	gFlagsOnce sync.Once
+)
+
 const (
 	Tag     feature.Tag = "console-gonnectian"
 	Version             = "0.2.1"
@@ -52,19 +63,56 @@ type MakeConsole interface {
 
 	SetGormDB(tag string) MakeConsole
 	SetTableName(table string) MakeConsole
+
+	AddSource(label, dbTag, table string) MakeConsole
 }
 
 type CConsole struct {
 	feature.CConsole
 
 	prefix string
+	demo   bool
 
 	dbName  string
 	dbTable string
 
-	db *gorm.DB
+	db   *gorm.DB
+	repo TenantRepository
+
+	sources []*TenantSource
+	source  int
+	// sourceLock guards repo and source, which the admin API reads from its
+	// own goroutines
+	sourceLock sync.RWMutex
+
+	features *feature.FeaturesCache
+
+	curses  *CCurses
+	sweeper chan struct{}
+
+	// asyncCalls counts the asyncCall functions which have not yet returned
+	asyncCalls atomic.Int64
+
+	// version is shown in the title and location is used to display times,
+	// the Harness blanks the one and uses UTC for the other
+	version  string
+	location *time.Location
+
+	apiListen string
+	apiToken  string
+	api       *AdminAPI
+
+	themeFlag   string
+	themeValue  string
+	themeLoaded *ConsoleTheme
+	// monochrome is set by the --gonnectian-monochrome flag, which is kept in
+	// monochromeFlag, or by NO_COLOR
+	monochrome     bool
+	monochromeFlag bool
+	noMouse        bool
 
-	curses *CCurses
+	prefsPath string
+	prefs     *Preferences
 
 	infoLabel ctk.Label
 	frame     ctk.Frame
@@ -81,6 +129,8 @@ func NewTagged(tag feature.Tag) MakeConsole {
 	f.Init(f)
 	f.PackageTag = Tag
 	f.ConsoleTag = tag
+	f.version = Version
+	f.location = time.Local
 	return f
 }
 
@@ -99,30 +149,52 @@ func (f *CConsole) SetTableName(table string) MakeConsole {
 }
 
 func (f *CConsole) Make() (c Console) {
-	if f.dbName == "" {
-		log.FatalDF(1, "%v feature requires .SetGormDB and .SetTableName", f.Tag())
-	} else if f.dbTable == "" {
-		log.FatalDF(1, "%v feature requires .SetTableName and .SetGormDB", f.Tag())
+	if f.dbName != "" || f.dbTable != "" || len(f.sources) == 0 {
+		if f.dbName == "" {
+			log.FatalDF(1, "%v feature requires .SetGormDB and .SetTableName", f.Tag())
+		} else if f.dbTable == "" {
+			log.FatalDF(1, "%v feature requires .SetTableName and .SetGormDB", f.Tag())
+		}
+		f.sources = append([]*TenantSource{{Label: f.dbTable, DbTag: f.dbName, Table: f.dbTable}}, f.sources...)
+	}
+	if err := validateSources(f.sources); err != nil {
+		log.FatalDF(1, "%v feature %v", f.Tag(), err)
 	}
 	return f
 }
 
 func (f *CConsole) Title() (title string) {
+	title = "Gonnectian"
+	if f.version != "" {
+		title += " v" + f.version
+	}
+	if globals.BinName != "" || globals.Version != "" {
+		title += fmt.Sprintf(" (%v %v)", globals.BinName, globals.Version)
+	}
 	if f.prefix != "" {
-		title = fmt.Sprintf("Gonnectian v%v (%v %v) [%v]", Version, globals.BinName, globals.Version, f.prefix)
-		return
+		title += fmt.Sprintf(" [%v]", f.prefix)
+	}
+	if f.demo {
+		title += " [demo]"
+	}
+	if src := f.Source(); src != nil && len(f.sources) > 1 {
+		title += fmt.Sprintf(" - %v", src.Label)
 	}
-	title = fmt.Sprintf("Gonnectian v%v (%v %v)", Version, globals.BinName, globals.Version)
 	return
 }
 
 func (f *CConsole) Build(b feature.Buildable) (err error) {
 	if err = f.CConsole.Build(b); err != nil {
 		return
-	} else if f.dbName == "" || f.dbTable == "" {
+	} else if len(f.sources) == 0 {
 		err = fmt.Errorf("%q feature requires .SetGormDB and .SetTableName", f.Tag())
 		return
 	}
+	f.features = b.Features()
+	b.AddCommands(f.makeCommand())
+	gFlagsOnce.Do(func() {
+		b.AddFlags(DemoFlag, APIListenFlag, APITokenFlag, ThemeFlag, MonochromeFlag, NoMouseFlag, PreferencesFlag)
+	})
 	log.DebugF("%v (v%v) build", Tag, Version)
 	return
 }
@@ -130,21 +202,37 @@ func (f *CConsole) Build(b feature.Buildable) (err error) {
 func (f *CConsole) Setup(ctx *cli.Context, ei feature.Internals) {
 	f.CConsole.Setup(ctx, ei)
 	f.prefix = ctx.String("prefix")
+	f.demo = ctx.Bool(DemoFlag.Name)
+	f.apiListen = ctx.String(APIListenFlag.Name)
+	f.apiToken = ctx.String(APITokenFlag.Name)
+	f.themeFlag = ctx.String(ThemeFlag.Name)
+	f.monochromeFlag = ctx.Bool(MonochromeFlag.Name)
+	f.monochrome = f.monochromeFlag || NoColor()
+	f.noMouse = ctx.Bool(NoMouseFlag.Name)
+	if f.prefsPath = ctx.String(PreferencesFlag.Name); f.prefsPath == "-" {
+		f.prefsPath = ""
+	} else if f.prefsPath == "" {
+		var err error
+		if f.prefsPath, err = DefaultPreferencesPath(); err != nil {
+			log.ErrorF("preferences disabled: %v", err)
+		}
+	}
 }
 
 func (f *CConsole) Prepare(app ctk.Application) {
 	f.CConsole.Prepare(app)
-	var ok bool
-	if v, err := f.Enjin.DB(f.dbName); err != nil {
-		log.PanicF("error getting enjin db %q: %v", f.dbName, err)
-	} else if f.db, ok = v.(*gorm.DB); !ok {
-		log.PanicF("error preparing enjin db; expected *gorm.DB, received: %T", v)
+	if err := f.prepareSources(f.Enjin.DB); err != nil {
+		log.PanicF("%v", err)
 	}
-	// no auto-migrate, manipulates features-gonnectian data
+	// no auto-migrate, manipulates features-gonnectian data and the context
+	// history table is only created by the migrate command
 }
 
 func (f *CConsole) Startup(display cdk.Display) {
 	f.CConsole.Startup(display)
+	if f.noMouse {
+		display.Screen().DisableMouse()
+	}
 
 	var err error
 	if f.curses, err = NewCurses(f); err != nil {
@@ -157,12 +245,52 @@ func (f *CConsole) Startup(display cdk.Display) {
 		return
 	}
 
+	f.loadPreferences()
+
+	if themeValue := startupTheme(f.themeFlag, f.prefs.Theme, f.monochromeFlag, NoColor()); themeValue != "" {
+		if f.themeLoaded, err = LoadTheme(themeValue); err == nil {
+			f.themeValue = themeValue
+			err = f.curses.applyTheme(f.themeLoaded)
+		}
+		if err != nil {
+			log.ErrorF("error loading theme: %v", err)
+		}
+	}
+
 	f.curses.Refresh()
+	f.startSweeper()
+	f.startAdminAPI()
 
 	f.Window().Show()
 	f.App().NotifyStartupComplete()
 }
 
+func (f *CConsole) Shutdown() {
+	if f.api != nil {
+		f.api.Stop()
+	}
+	f.stopSweeper()
+	f.savePreferences()
+	f.CConsole.Shutdown()
+}
+
+// startAdminAPI serves the admin API alongside the user interface when the
+// APIListenFlag is set, errors are logged and do not prevent the console from
+// running
+func (f *CConsole) startAdminAPI() {
+	if f.apiListen == "" {
+		return
+	}
+	var err error
+	if f.api, err = NewAdminAPI(f, f.apiToken); err == nil {
+		err = f.api.Start(f.apiListen)
+	}
+	if err != nil {
+		log.ErrorF("%v", err)
+		f.api = nil
+	}
+}
+
 func (f *CConsole) Resized(w, h int) {
 	log.DebugF("refreshing on resized: %v, %v", w, h)
 	f.Refresh()
@@ -185,12 +313,42 @@ func (f *CConsole) Refresh() {
 	f.curses.Refresh()
 }
 
-func (f *CConsole) tx() (tx *gorm.DB) {
-	tx = f.db.Scopes(func(tx *gorm.DB) *gorm.DB {
-		if f.dbTable == "" {
-			return tx.Table(store.DefaultTableName)
-		}
-		return tx.Table(f.dbTable)
-	})
+// requestRefresh refreshes the user interface, if running, from within the
+// display event processing rather than the calling goroutine
+func (f *CConsole) requestRefresh() {
+	if f.curses == nil {
+		return
+	}
+	if err := f.asyncCall(f.Refresh); err != nil {
+		log.DebugF("refresh not requested: %v", err)
+	}
+}
+
+// asyncCall runs fn from within the display event processing rather than the
+// calling goroutine, counting it in asyncCalls until it returns
+func (f *CConsole) asyncCall(fn func()) (err error) {
+	f.asyncCalls.Add(1)
+	if err = f.Display().AsyncCall(func(_ cdk.Display) error {
+		defer f.asyncCalls.Add(-1)
+		fn()
+		return nil
+	}); err != nil {
+		f.asyncCalls.Add(-1)
+	}
+	return
+}
+
+// appDescriptors returns the Atlassian Connect apps served by this enjin, or
+// the DemoApps in demo mode
+func (f *CConsole) appDescriptors() (apps []AppDescriptor) {
+	if f.demo {
+		return DemoApps()
+	}
+	for _, gf := range feature.FilterTyped[gonnectian.Feature](f.features.List()) {
+		apps = append(apps, AppDescriptor{
+			URL:        gf.GetPluginInstallationURL(),
+			Descriptor: gf.GetPluginDescriptor(),
+		})
+	}
 	return
 }
@@ -0,0 +1,128 @@
+//go:build (curses || all) && harness
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SnapshotSize is a terminal size used for rendering golden snapshots
+type SnapshotSize struct {
+	Width  int
+	Height int
+}
+
+func (s SnapshotSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+var (
+	// GoldenDir is the directory containing the golden snapshot files
+	GoldenDir = "testdata"
+
+	// UpdateGolden rewrites golden files with the current screen contents
+	// instead of comparing them, the tests set it with the -update flag:
+	//
+	//	go test -tags curses,harness -run Snapshots . -update
+	UpdateGolden = false
+
+	// SnapshotSizes are the terminal sizes used by MatchSnapshots, covering
+	// the narrow, default and wide layouts of the panels
+	SnapshotSizes = []SnapshotSize{
+		{Width: 80, Height: 24},
+		{Width: 100, Height: 30},
+		{Width: 120, Height: 40},
+	}
+)
+
+// GoldenPath returns the golden file path for the named snapshot
+func GoldenPath(name string) (path string) {
+	return filepath.Join(GoldenDir, name+".golden")
+}
+
+// MatchSnapshot compares the current screen text with the named golden file,
+// returning an error listing the differing lines; when UpdateGolden is set
+// the golden file is written instead
+func (h *Harness) MatchSnapshot(name string) (err error) {
+	path := GoldenPath(name)
+	actual := h.ScreenText() + "\n"
+
+	if UpdateGolden {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return
+		}
+		err = os.WriteFile(path, []byte(actual), 0644)
+		return
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("error reading golden file (run the tests with -update to create): %v", err)
+		return
+	}
+	if expected := string(data); expected != actual {
+		err = fmt.Errorf("snapshot %v does not match %v:\n%v", name, path, DiffSnapshots(expected, actual))
+	}
+	return
+}
+
+// MatchSnapshots resizes the screen to each of the SnapshotSizes and matches
+// the golden files named "<name>-<width>x<height>", all sizes are checked
+// before returning the combined errors
+func (h *Harness) MatchSnapshots(name string) (err error) {
+	var errs []string
+	for _, size := range SnapshotSizes {
+		h.Resize(size.Width, size.Height)
+		if ee := h.MatchSnapshot(name + "-" + size.String()); ee != nil {
+			errs = append(errs, ee.Error())
+		}
+	}
+	h.Resize(HarnessWidth, HarnessHeight)
+	if len(errs) > 0 {
+		err = fmt.Errorf("%v", strings.Join(errs, "\n"))
+	}
+	return
+}
+
+// DiffSnapshots returns a line-by-line comparison of the expected and actual
+// screen text, listing only the rows which differ
+func DiffSnapshots(expected, actual string) (diff string) {
+	el := strings.Split(expected, "\n")
+	al := strings.Split(actual, "\n")
+	count := len(el)
+	if len(al) > count {
+		count = len(al)
+	}
+	var lines []string
+	for idx := 0; idx < count; idx++ {
+		var e, a string
+		if idx < len(el) {
+			e = el[idx]
+		}
+		if idx < len(al) {
+			a = al[idx]
+		}
+		if e != a {
+			lines = append(lines, fmt.Sprintf("row %d:\n- %v\n+ %v", idx+1, e, a))
+		}
+	}
+	diff = strings.Join(lines, "\n")
+	return
+}
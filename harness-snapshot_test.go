@@ -0,0 +1,72 @@
+//go:build (curses || all) && harness
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-curses/cdk"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden snapshot files")
+
+// snapshotPanels are the golden snapshots of each panel, showing the
+// HarnessTenants
+var snapshotPanels = []struct {
+	name string
+	key  cdk.Key
+	text string
+}{
+	{name: "tenants", key: cdk.KeyF3, text: "3 tenants found"},
+	{name: "appinfo", key: cdk.KeyF2, text: "0 applications, 0 total versions"},
+}
+
+func TestHarnessSnapshots(t *testing.T) {
+	UpdateGolden = *update
+	h := startHarness(t)
+	for _, panel := range snapshotPanels {
+		h.SendKey(panel.key, 0)
+		expectText(t, h, panel.text)
+		if err := h.MatchSnapshots(panel.name); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestHarnessSnapshotsGolden(t *testing.T) {
+	expected := make(map[string]bool)
+	for _, panel := range snapshotPanels {
+		for _, size := range SnapshotSizes {
+			expected[GoldenPath(panel.name+"-"+size.String())] = true
+		}
+	}
+	found, err := filepath.Glob(GoldenPath("*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range found {
+		if !expected[path] {
+			t.Errorf("golden file %v is not compared by any snapshot", path)
+		}
+		delete(expected, path)
+	}
+	for path := range expected {
+		t.Errorf("golden file %v is missing (run the tests with -update to create)", path)
+	}
+}
@@ -0,0 +1,371 @@
+//go:build (curses || all) && harness
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/creack/pty"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/feature"
+	"github.com/go-enjin/be/pkg/log"
+)
+
+var (
+	HarnessWidth   = 100
+	HarnessHeight  = 30
+	HarnessTimeout = 5 * time.Second
+
+	// HarnessTerm is the terminal type of the harness pseudo-terminal
+	HarnessTerm = "xterm-256color"
+)
+
+// Harness runs a CConsole against an in-memory SQLite database, for exercising
+// the panels and handlers without a terminal. The display is attached to a
+// pseudo-terminal rather than the cdk offscreen, as the offscreen cursor
+// handling unlocks a mutex it never locked, which is fatal as soon as a dialog
+// or entry updates the cursor, and the events posted to the offscreen are
+// never delivered. Only one Harness may be running at a time and TERM is set
+// to HarnessTerm. Build with the "harness" tag:
+//
+//	go test -tags curses,harness ./...
+type Harness struct {
+	Console *CConsole
+	App     ctk.Application
+	DB      *gorm.DB
+
+	pty     *os.File
+	tty     *os.File
+	started chan struct{}
+	stopped chan struct{}
+	// resized is the size of the last resize processed by the display
+	resized atomic.Value
+}
+
+// NewHarness seeds a new in-memory database with the given tenants and starts
+// the console user interface on a pseudo-terminal of HarnessWidth by
+// HarnessHeight cells
+func NewHarness(tenants ...*store.Tenant) (h *Harness, err error) {
+	h = &Harness{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	// each harness uses a uniquely named shared-cache memory database so that
+	// all pooled connections see the same data
+	dsn := fmt.Sprintf("file:harness-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	if h.DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard}); err != nil {
+		err = fmt.Errorf("error opening harness db: %v", err)
+		return
+	} else if err = h.DB.Table(store.DefaultTableName).AutoMigrate(&store.Tenant{}); err != nil {
+		err = fmt.Errorf("error migrating harness db: %v", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if err = h.DB.Table(store.DefaultTableName).Create(tenant).Error; err != nil {
+			err = fmt.Errorf("error seeding harness db: %v - %v", tenant.BaseURL, err)
+			return
+		}
+	}
+
+	h.Console = NewTagged(Tag + "-harness").
+		SetGormDB("harness").
+		SetTableName(store.DefaultTableName).
+		Make().(*CConsole)
+	h.Console.db = h.DB
+	// the version and local time zone would change the snapshots
+	h.Console.version = ""
+	h.Console.location = time.UTC
+	repo := NewGormTenantRepository(h.DB, store.DefaultTableName)
+	if err = repo.MigrateHistory(); err != nil {
+		return
+	}
+	h.Console.sources[0].repo = repo
+	if err = h.Console.SelectSource(0); err != nil {
+		return
+	}
+	h.Console.features = feature.NewFeaturesCache()
+
+	// tooltip timers outlive the display and would fire against the display
+	// of the next harness
+	ctk.GetDefaultSettings().SetCtkEnableTooltips(false)
+
+	if h.pty, h.tty, err = pty.Open(); err != nil {
+		err = fmt.Errorf("error opening harness pseudo-terminal: %v", err)
+		return
+	} else if err = pty.Setsize(h.pty, &pty.Winsize{Cols: uint16(HarnessWidth), Rows: uint16(HarnessHeight)}); err != nil {
+		err = fmt.Errorf("error sizing harness pseudo-terminal: %v", err)
+		return
+	}
+	// the terminal output is never read, the screen contents are taken from
+	// the display screen instead
+	go func() {
+		_, _ = io.Copy(io.Discard, h.pty)
+	}()
+	if err = os.Setenv("TERM", HarnessTerm); err != nil {
+		return
+	}
+
+	h.App = ctk.NewApplication(
+		"harness", "harness", "gonnectian console harness",
+		Version, "harness", "harness",
+		h.tty.Name(),
+	)
+	h.App.Connect(cdk.SignalPrepare, "gonnectian-harness-prepare-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		// the sources are prepared above without an enjin
+		h.Console.CConsole.Prepare(h.App)
+		return cenums.EVENT_PASS
+	})
+	h.App.Connect(cdk.SignalStartup, "gonnectian-harness-startup-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		if _, display, _, _, _, ok := ctk.ArgvApplicationSignalStartup(argv...); ok {
+			display.Connect(cdk.SignalEventResize, "gonnectian-harness-resize-handler", h.resizeHandler)
+			h.Console.Startup(display)
+			close(h.started)
+			return cenums.EVENT_PASS
+		}
+		return cenums.EVENT_STOP
+	})
+	h.App.Connect(cdk.SignalShutdown, "gonnectian-harness-shutdown-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		h.Console.Shutdown()
+		return cenums.EVENT_PASS
+	})
+
+	go func() {
+		defer close(h.stopped)
+		if ee := h.App.Run([]string{"harness"}); ee != nil {
+			log.ErrorF("error running harness: %v", ee)
+		}
+	}()
+
+	select {
+	case <-h.started:
+	case <-h.stopped:
+		err = fmt.Errorf("harness stopped during startup")
+		return
+	case <-time.After(HarnessTimeout):
+		err = fmt.Errorf("timeout waiting for harness startup")
+		return
+	}
+	if !h.waitUntil(func() bool { return h.resized.Load() == [2]int{HarnessWidth, HarnessHeight} }) {
+		err = fmt.Errorf("timeout waiting for the harness startup resize")
+		return
+	}
+	h.Settle()
+	return
+}
+
+func (h *Harness) resizeHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) >= 2 {
+		if evt, ok := argv[1].(*cdk.EventResize); ok {
+			w, ht := evt.Size()
+			h.Console.Resized(w, ht)
+			h.resized.Store([2]int{w, ht})
+		}
+	}
+	return cenums.EVENT_PASS
+}
+
+func (h *Harness) waitUntil(fn func() bool) (ok bool) {
+	deadline := time.Now().Add(HarnessTimeout)
+	for time.Now().Before(deadline) {
+		if ok = fn(); ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return
+}
+
+// Display returns the running display
+func (h *Harness) Display() cdk.Display {
+	return h.Console.Display()
+}
+
+// Resize changes the terminal size and redraws the screen
+func (h *Harness) Resize(width, height int) {
+	if err := pty.Setsize(h.pty, &pty.Winsize{Cols: uint16(width), Rows: uint16(height)}); err != nil {
+		log.ErrorF("error resizing harness pseudo-terminal: %v", err)
+		return
+	}
+	// the harness is not the foreground process of the terminal and receives
+	// no SIGWINCH, a sync has the screen read the new size and post a resize
+	h.Display().ProcessEvent(cdk.NewEventDrawAndSync())
+	if !h.waitUntil(func() bool { return h.resized.Load() == [2]int{width, height} }) {
+		log.ErrorF("timeout waiting for the harness resize to %vx%v", width, height)
+	}
+	h.Settle()
+}
+
+// SendKey processes a key event, for example SendKey(cdk.KeyF3, 0) or
+// SendKey(cdk.KeyRune, 'j'), and redraws the screen; control keys such as
+// cdk.KeyEnter and cdk.KeyBackspace2 are given their rune as a terminal would
+func (h *Harness) SendKey(key cdk.Key, r rune) {
+	if r == 0 && (key < ' ' || key == cdk.KeyBackspace2) {
+		r = rune(key)
+	}
+	h.Display().ProcessEvent(cdk.NewEventKey(key, r, cdk.ModNone))
+	h.Settle()
+}
+
+// SendMouse processes a mouse event at the given cell, for example a click is
+// SendMouse(x, y, cdk.Button1) followed by SendMouse(x, y, cdk.ButtonNone),
+// and redraws the screen
+func (h *Harness) SendMouse(x, y int, buttons cdk.ButtonMask) {
+	h.Display().ProcessEvent(cdk.NewEventMouse(x, y, buttons, cdk.ModNone))
+	h.Settle()
+}
+
+// DoubleClick processes two clicks at the given cell without settling between
+// them, so that they fall within the DoubleClickInterval however slow the
+// redraws are, and redraws the screen
+func (h *Harness) DoubleClick(x, y int) {
+	for i := 0; i < 2; i++ {
+		h.Display().ProcessEvent(cdk.NewEventMouse(x, y, cdk.Button1, cdk.ModNone))
+		h.Display().ProcessEvent(cdk.NewEventMouse(x, y, cdk.ButtonNone, cdk.ModNone))
+	}
+	h.Settle()
+}
+
+// Settle waits for the console asynchronous calls, such as dialog callbacks
+// and refreshes, to return and then renders the screen; the events the display
+// posts to itself are only requests to render
+func (h *Harness) Settle() {
+	if !h.waitUntil(func() bool { return h.Console.asyncCalls.Load() == 0 }) {
+		log.ErrorF("timeout waiting for harness asynchronous calls")
+	}
+	if h.Display().IsRunning() {
+		h.Display().ProcessEvent(cdk.NewEventDrawAndShow())
+	}
+}
+
+// ScreenText returns the current screen contents as plain text, one line per
+// row with trailing spaces removed
+func (h *Harness) ScreenText() (text string) {
+	screen := h.Display().Screen()
+	if screen == nil {
+		return
+	}
+	w, ht := screen.Size()
+	lines := make([]string, 0, ht)
+	for y := 0; y < ht; y++ {
+		var line strings.Builder
+		for x := 0; x < w; x++ {
+			if r, _, _, _ := screen.GetContent(x, y); r != 0 {
+				line.WriteRune(r)
+			} else {
+				line.WriteRune(' ')
+			}
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	text = strings.Join(lines, "\n")
+	return
+}
+
+// WaitForText settles the screen until it contains the given text, returning
+// false on timeout; changes made from other goroutines, such as by the admin
+// API, are only drawn once settled
+func (h *Harness) WaitForText(text string) (ok bool) {
+	return h.waitUntil(func() bool {
+		if strings.Contains(h.ScreenText(), text) {
+			return true
+		}
+		h.Settle()
+		return strings.Contains(h.ScreenText(), text)
+	})
+}
+
+// Tenant reloads the tenant with the given ClientKey from the database
+func (h *Harness) Tenant(clientKey string) (tenant *store.Tenant, err error) {
+	tenant, err = h.Console.currentRepo().Get(clientKey)
+	return
+}
+
+// Close cancels any dialogs still shown, so that none of their callbacks run
+// after the display is gone, quits the display and waits for the console
+// application to return
+func (h *Harness) Close() (err error) {
+	defer func() {
+		_ = h.tty.Close()
+		_ = h.pty.Close()
+	}()
+	if h.Console.curses != nil && h.Display().IsRunning() {
+		if ee := h.Console.asyncCall(h.Console.curses.cancelDialogs); ee == nil {
+			h.Settle()
+		}
+		h.Display().RequestQuit()
+	}
+	select {
+	case <-h.stopped:
+	case <-time.After(HarnessTimeout):
+		err = fmt.Errorf("timeout waiting for harness shutdown")
+	}
+	return
+}
+
+// HarnessTenants returns a small set of tenant fixtures covering the various
+// context states displayed by the TenantsPanel
+func HarnessTenants() (tenants []*store.Tenant) {
+	created := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tenants = []*store.Tenant{
+		{
+			ClientKey:      "harness-client-1",
+			SharedSecret:   "secret-1",
+			BaseURL:        "https://alpha.atlassian.net",
+			ProductType:    "jira",
+			AddonInstalled: true,
+			CreatedAt:      created,
+			UpdatedAt:      created,
+			Context:        []byte(`{"debug":"false","license":"active"}`),
+		},
+		{
+			ClientKey:      "harness-client-2",
+			SharedSecret:   "secret-2",
+			BaseURL:        "https://beta.atlassian.net",
+			ProductType:    "confluence",
+			AddonInstalled: true,
+			CreatedAt:      created.Add(24 * time.Hour),
+			UpdatedAt:      created.Add(48 * time.Hour),
+			Context:        []byte(`{"debug":"true","license":"none","allowed-unlicensed":true}`),
+		},
+		{
+			ClientKey:      "harness-client-3",
+			SharedSecret:   "secret-3",
+			BaseURL:        "https://gamma.atlassian.net",
+			ProductType:    "jira",
+			AddonInstalled: false,
+			CreatedAt:      created.Add(72 * time.Hour),
+			UpdatedAt:      created.Add(96 * time.Hour),
+			Context:        []byte(`{"debug":"false","license":"none","flagged":"abuse","flagged-at":"2023-06-10T00:00:00Z"}`),
+		},
+	}
+	return
+}
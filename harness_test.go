@@ -0,0 +1,711 @@
+//go:build (curses || all) && harness
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-curses/cdk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+const (
+	alphaKey = "harness-client-1"
+	betaKey  = "harness-client-2"
+	gammaKey = "harness-client-3"
+)
+
+// startHarness runs a harness with the HarnessTenants showing the tenants
+// panel, with the cursor on the first tenant
+func startHarness(t *testing.T) (h *Harness) {
+	t.Helper()
+	t.Setenv("TMPDIR", t.TempDir())
+	var err error
+	if h, err = NewHarness(HarnessTenants()...); err != nil {
+		t.Fatalf("error starting harness: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := h.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	h.SendKey(cdk.KeyF3, 0)
+	expectText(t, h, "3 tenants found")
+	h.SendKey(cdk.KeyRune, 'g')
+	return
+}
+
+func typeText(h *Harness, text string) {
+	for _, r := range text {
+		h.SendKey(cdk.KeyRune, r)
+	}
+}
+
+// runCommand runs the first command palette match for the query
+func runCommand(t *testing.T, h *Harness, query string) {
+	t.Helper()
+	h.SendKey(cdk.KeyCtrlP, 0)
+	expectText(t, h, "Commands")
+	typeText(h, query)
+	h.SendKey(cdk.KeyEnter, 0)
+}
+
+// clickText clicks on the first occurrence of the text on screen, such as the
+// label of a button or a menu option
+func clickText(t *testing.T, h *Harness, text string) {
+	t.Helper()
+	x, y := textPosition(t, h, text)
+	h.SendMouse(x, y, cdk.Button1)
+	h.SendMouse(x, y, cdk.ButtonNone)
+}
+
+func doubleClickText(t *testing.T, h *Harness, text string) {
+	t.Helper()
+	x, y := textPosition(t, h, text)
+	h.DoubleClick(x, y)
+}
+
+// textPosition waits for the text and returns the cell it starts at
+func textPosition(t *testing.T, h *Harness, text string) (x, y int) {
+	t.Helper()
+	expectText(t, h, text)
+	for y, line := range strings.Split(h.ScreenText(), "\n") {
+		if idx := strings.Index(line, text); idx >= 0 {
+			return utf8.RuneCountInString(line[:idx]), y
+		}
+	}
+	t.Fatalf("expected %q on screen", text)
+	return
+}
+
+func expectText(t *testing.T, h *Harness, text string) {
+	t.Helper()
+	if !h.WaitForText(text) {
+		t.Fatalf("expected %q on screen:\n%v", text, h.ScreenText())
+	}
+}
+
+// expectNoText waits for the text to be gone from the screen
+func expectNoText(t *testing.T, h *Harness, text string) {
+	t.Helper()
+	if !h.waitUntil(func() bool { return !strings.Contains(h.ScreenText(), text) }) {
+		t.Fatalf("unexpected %q on screen:\n%v", text, h.ScreenText())
+	}
+}
+
+// closeDialog closes the dialog presented with the given title
+func closeDialog(t *testing.T, h *Harness, title string) {
+	t.Helper()
+	expectText(t, h, title)
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, title)
+}
+
+func tenantContext(t *testing.T, h *Harness, clientKey string) (ctx TenantContext) {
+	t.Helper()
+	tenant, err := h.Tenant(clientKey)
+	if err != nil {
+		t.Fatalf("error loading tenant: %v", err)
+	}
+	if ctx, err = ParseTenantContext(tenant); err != nil {
+		t.Fatalf("error parsing tenant context: %v", err)
+	}
+	return
+}
+
+func tenantsPanel(h *Harness) *TenantsPanel {
+	return h.Console.curses.panels["tenants"].(*TenantsPanel)
+}
+
+func TestHarnessPanels(t *testing.T) {
+	h := startHarness(t)
+	expectText(t, h, "https://alpha.atlassian.net (lic=active)")
+	expectText(t, h, "https://gamma.atlassian.net (lic=none) [FLAGGED]")
+	expectText(t, h, ": abuse")
+
+	h.SendKey(cdk.KeyF2, 0)
+	expectText(t, h, "0 applications, 0 total versions")
+	expectNoText(t, h, "tenants found")
+
+	h.SendKey(cdk.KeyF3, 0)
+	expectText(t, h, "3 tenants found")
+}
+
+func TestHarnessEnableDebug(t *testing.T) {
+	h := startHarness(t)
+	runCommand(t, h, "enable debug")
+	expectText(t, h, "Disable debugging automatically after:")
+	clickText(t, h, "24 hours")
+	expectText(t, h, "(installed,  debugging enabled")
+	if ctx := tenantContext(t, h, alphaKey); !ctx.Debug() {
+		t.Fatalf("expected debug enabled: %v", ctx)
+	} else if _, ok := ctx.DebugExpires(); !ok {
+		t.Fatalf("expected debug expiry: %v", ctx)
+	}
+
+	runCommand(t, h, "disable debug")
+	expectText(t, h, "(installed,  debugging disabled)")
+	if ctx := tenantContext(t, h, alphaKey); ctx.Debug() {
+		t.Fatalf("expected debug disabled: %v", ctx)
+	}
+}
+
+func TestHarnessFlag(t *testing.T) {
+	h := startHarness(t)
+	runCommand(t, h, "flag")
+	expectText(t, h, "Note on why https://alpha.atlassian.net")
+	typeText(h, "spam")
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, "https://alpha.atlassian.net (lic=active) [FLAGGED]")
+	expectText(t, h, ": spam")
+	if ctx := tenantContext(t, h, alphaKey); !ctx.Flagged() || ctx.FlagReason() != "spam" {
+		t.Fatalf("expected flagged for spam: %v", ctx)
+	}
+
+	runCommand(t, h, "unflag")
+	expectNoText(t, h, ": spam")
+	if ctx := tenantContext(t, h, alphaKey); ctx.Flagged() {
+		t.Fatalf("expected unflagged: %v", ctx)
+	}
+}
+
+func TestHarnessDebugCustomExpiry(t *testing.T) {
+	h := startHarness(t)
+	clickText(t, h, "Enable Debug")
+	clickText(t, h, "Custom...")
+	expectText(t, h, "Expires after")
+	typeText(h, "3d")
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, "(installed,  debugging enabled")
+	ctx := tenantContext(t, h, alphaKey)
+	if expires, ok := ctx.DebugExpires(); !ok || expires.Before(time.Now().Add(71*time.Hour)) {
+		t.Fatalf("expected debug to expire in 3 days: %v", ctx)
+	}
+}
+
+func TestHarnessAllowUnlicensed(t *testing.T) {
+	h := startHarness(t)
+	runCommand(t, h, "allow unlicensed")
+	clickText(t, h, "30 days")
+	expectText(t, h, "Reason for the grant")
+	typeText(h, "evaluation")
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, "(installed,  allowed unlicensed 29d")
+	expectText(t, h, "unlicensed grant: evaluation")
+	if ctx := tenantContext(t, h, alphaKey); !ctx.AllowedUnlicensed() || ctx.UnlicensedReason() != "evaluation" {
+		t.Fatalf("expected unlicensed allowed for evaluation: %v", ctx)
+	}
+
+	runCommand(t, h, "reject unlicensed")
+	expectNoText(t, h, "unlicensed grant: evaluation")
+	if ctx := tenantContext(t, h, alphaKey); ctx.AllowedUnlicensed() {
+		t.Fatalf("expected unlicensed rejected: %v", ctx)
+	}
+}
+
+func TestHarnessFlagButton(t *testing.T) {
+	h := startHarness(t)
+	clickText(t, h, "Flag Tenant")
+	expectText(t, h, "Note on why https://alpha.atlassian.net")
+	typeText(h, "fraud")
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, "Note on why")
+	if ctx := tenantContext(t, h, alphaKey); ctx.Flagged() {
+		t.Fatalf("expected a cancelled flag to change nothing: %v", ctx)
+	}
+}
+
+func TestHarnessLongNote(t *testing.T) {
+	h := startHarness(t)
+	runCommand(t, h, "flag")
+	expectText(t, h, "Note on why https://alpha.atlassian.net")
+	typeText(h, strings.Repeat("word ", 40)+"end")
+	h.SendKey(cdk.KeyEnter, 0)
+	// the card grows with the wrapped lines of its text
+	expectText(t, h, "word end)")
+	if top, height := tenantsPanel(h).rowSpan(0); top != 0 || height <= cardMinHeight {
+		t.Fatalf("expected the first card taller than %v lines: %v, %v", cardMinHeight, top, height)
+	}
+
+	// the last card scrolls fully into view below it
+	h.SendKey(cdk.KeyRune, 'G')
+	expectNoText(t, h, "[1] https://alpha.atlassian.net")
+	expectText(t, h, "[3] https://gamma.atlassian.net")
+	expectText(t, h, ": abuse)")
+}
+
+func TestHarnessInvalidContext(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	created := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tenants := append(HarnessTenants(), &store.Tenant{
+		ClientKey:    "harness-client-4",
+		SharedSecret: "secret-4",
+		BaseURL:      "https://zeta.atlassian.net",
+		CreatedAt:    created,
+		UpdatedAt:    created,
+		Context:      []byte(`{broken`),
+	})
+	h, err := NewHarness(tenants...)
+	if err != nil {
+		t.Fatalf("error starting harness: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := h.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	h.SendKey(cdk.KeyF3, 0)
+	expectText(t, h, "4 tenants found")
+
+	// the card has a badge and no buttons, nor can it be selected
+	h.SendKey(cdk.KeyRune, 'G')
+	expectText(t, h, "[4] https://zeta.atlassian.net [INVALID CONTEXT]")
+	expectText(t, h, "the tenant is read-only")
+	panel := tenantsPanel(h)
+	if tenant, ok := panel.currentTenant(); !ok || tenant.ClientKey != "harness-client-4" {
+		t.Fatalf("expected the cursor on the invalid tenant: %v", tenant)
+	}
+	h.SendKey(cdk.KeyRune, 'x')
+	runCommand(t, h, "select all tenants")
+	expectText(t, h, "Batch (3)")
+	if panel.selected["harness-client-4"] {
+		t.Fatalf("expected the invalid tenant not to be selected")
+	}
+
+	var names []string
+	tenant, _ := h.Tenant("harness-client-4")
+	for _, command := range panel.tenantCommands(tenant, nil) {
+		names = append(names, command.Name)
+	}
+	if expected := []string{"Show details: https://zeta.atlassian.net", "Copy: https://zeta.atlassian.net"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected commands %v, found %v", expected, names)
+	}
+
+	h.SendKey(cdk.KeyRune, 'v')
+	expectText(t, h, "invalid")
+}
+
+func TestHarnessSelectAndBatch(t *testing.T) {
+	h := startHarness(t)
+	h.SendKey(cdk.KeyRune, 'x')
+	h.SendKey(cdk.KeyRune, 'j')
+	h.SendKey(cdk.KeyRune, 'x')
+	if p := tenantsPanel(h); !p.selected[alphaKey] || !p.selected[betaKey] || len(p.selected) != 2 {
+		t.Fatalf("expected alpha and beta selected: %v", p.selected)
+	}
+	expectText(t, h, "[x] Select")
+
+	runCommand(t, h, "batch actions")
+	expectText(t, h, "apply to 2 selected tenants:")
+	clickText(t, h, "Enable Debug...")
+	clickText(t, h, "1 hour")
+	closeDialog(t, h, "Batch Complete")
+	for _, key := range []string{alphaKey, betaKey} {
+		if ctx := tenantContext(t, h, key); !ctx.Debug() {
+			t.Fatalf("expected debug enabled for %v: %v", key, ctx)
+		}
+	}
+	if ctx := tenantContext(t, h, gammaKey); ctx.Debug() {
+		t.Fatalf("expected the unselected tenant unchanged: %v", ctx)
+	}
+
+	runCommand(t, h, "batch actions")
+	clickText(t, h, "Compare")
+	expectText(t, h, "https://beta.atlassian.net")
+	closeDialog(t, h, "Compare")
+
+	runCommand(t, h, "clear the selection")
+	expectNoText(t, h, "[x] Select")
+	if p := tenantsPanel(h); len(p.selected) != 0 {
+		t.Fatalf("expected no selection: %v", p.selected)
+	}
+}
+
+func TestHarnessBatchDelete(t *testing.T) {
+	h := startHarness(t)
+	h.SendKey(cdk.KeyRune, 'G')
+	h.SendKey(cdk.KeyRune, 'x')
+	runCommand(t, h, "batch actions")
+	clickText(t, h, "Delete")
+	expectText(t, h, "Permanently delete 1 tenants?")
+	clickText(t, h, "Yes")
+	closeDialog(t, h, "Delete")
+	expectText(t, h, "2 tenants found")
+	if _, err := h.Tenant(gammaKey); err == nil {
+		t.Fatalf("expected %v to be deleted", gammaKey)
+	}
+}
+
+func TestHarnessCopy(t *testing.T) {
+	h := startHarness(t)
+	clickText(t, h, "More...")
+	clickText(t, h, "Copy...")
+	expectText(t, h, "Copy from https://alpha.atlassian.net:")
+	clickText(t, h, "Client Key")
+	// the harness runs within a pseudo-terminal, which takes the OSC52 copy
+	expectText(t, h, "Client Key sent to the terminal clipboard")
+	if paths, _ := filepath.Glob(filepath.Join(os.TempDir(), "*-copy-*.txt")); len(paths) != 0 {
+		t.Fatalf("expected no copy file with a terminal clipboard, found: %v", paths)
+	}
+	closeDialog(t, h, "Client Key sent to the terminal clipboard")
+}
+
+func TestHarnessHistory(t *testing.T) {
+	size := HistoryMenuSize
+	HistoryMenuSize = 1
+	t.Cleanup(func() { HistoryMenuSize = size })
+
+	h := startHarness(t)
+	runCommand(t, h, "history")
+	expectText(t, h, "No context changes have been recorded for:")
+	closeDialog(t, h, "No context changes")
+
+	runCommand(t, h, "enable debug")
+	clickText(t, h, "No expiry")
+	expectText(t, h, "(installed,  debugging enabled")
+
+	clickText(t, h, "More...")
+	clickText(t, h, "History...")
+	expectText(t, h, "Context versions of https://alpha.atlassian.net:")
+	expectText(t, h, "A roll back restores only the debug, unlicensed and flag settings.")
+	clickText(t, h, "v2 ")
+	clickText(t, h, "Show context")
+	expectText(t, h, `"debug": "true"`)
+	closeDialog(t, h, "Version 2")
+
+	// features-gonnectian updates the license meanwhile
+	tenant, err := h.Tenant(alphaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenantContext(t, h, alphaKey)
+	ctx[CtxKeyLicense] = "none"
+	if err = ctx.Apply(tenant); err != nil {
+		t.Fatal(err)
+	} else if err = h.DB.Table(store.DefaultTableName).Where("client_key = ?", alphaKey).Update("context", tenant.Context).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	runCommand(t, h, "history")
+	expectNoText(t, h, "v1 ")
+	clickText(t, h, "Older versions (1 more)...")
+	clickText(t, h, "v1 ")
+	clickText(t, h, "Roll back to this version")
+	expectText(t, h, "Restore the debug, unlicensed and flag")
+	expectText(t, h, "as they are: license")
+	clickText(t, h, "Yes")
+	closeDialog(t, h, "Roll Back")
+	expectText(t, h, "(installed,  debugging disabled)")
+	if ctx := tenantContext(t, h, alphaKey); ctx.Debug() {
+		t.Fatalf("expected debug disabled after the roll back: %v", ctx)
+	} else if ctx.License() != "none" {
+		t.Fatalf("expected the license kept by the roll back: %v", ctx)
+	}
+}
+
+func TestHarnessHistoryMigrate(t *testing.T) {
+	h := startHarness(t)
+	repo := h.Console.currentRepo().(*GormTenantRepository)
+	if err := h.DB.Migrator().DropTable(repo.historyTable()); err != nil {
+		t.Fatal(err)
+	} else if repo.DetectHistory() {
+		t.Fatal("expected the history disabled without its table")
+	}
+	runCommand(t, h, "enable debug")
+	clickText(t, h, "No expiry")
+	expectText(t, h, "(installed,  debugging enabled")
+	runCommand(t, h, "history")
+	expectText(t, h, "context history is disabled")
+	closeDialog(t, h, "History")
+
+	if err := repo.MigrateHistory(); err != nil {
+		t.Fatal(err)
+	}
+	duplicate := []*ContextSnapshot{{ClientKey: alphaKey, Version: 1}, {ClientKey: alphaKey, Version: 1}}
+	if err := h.DB.Table(repo.historyTable()).Create(duplicate).Error; err == nil {
+		t.Fatal("expected the versions of a tenant to be unique")
+	}
+}
+
+func TestHarnessAdminAPI(t *testing.T) {
+	h := startHarness(t)
+	api, err := NewAdminAPI(h.Console, "harness-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(method, path, auth, body string) (status int, response string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		return w.Code, w.Body.String()
+	}
+	path := "/api/tenants/" + alphaKey
+	for _, other := range []string{"/x/tenants/" + alphaKey, "/x/tenants/" + alphaKey + "/history", "/api/tenant/" + alphaKey} {
+		if status, _ := request(http.MethodGet, other, "Bearer harness-token", ""); status != http.StatusNotFound {
+			t.Errorf("expected %q not found: %v", other, status)
+		}
+	}
+	for _, auth := range []string{"", "harness-token", "Basic harness-token", "Bearer wrong"} {
+		if status, _ := request(http.MethodGet, path, auth, ""); status != http.StatusUnauthorized {
+			t.Errorf("expected %q unauthorized: %v", auth, status)
+		}
+	}
+	for _, body := range []string{`{"select": {"clientKey": "*"}, "debug": {"enabled": true}}`, `{}`, `{"reboot": true}`} {
+		if status, response := request(http.MethodPatch, path, "Bearer harness-token", body); status != http.StatusBadRequest {
+			t.Errorf("expected %v to be refused: %v %v", body, status, response)
+		}
+	}
+	large := `{"unset": ["` + strings.Repeat("x", MaxRequestBody) + `"]}`
+	for _, method := range []string{http.MethodPatch, http.MethodPost} {
+		target := path
+		if method == http.MethodPost {
+			target += "/rollback"
+		}
+		if status, _ := request(method, target, "Bearer harness-token", large); status != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected the %v body refused as too large: %v", method, status)
+		}
+	}
+	if status, response := request(http.MethodPatch, path, "Bearer harness-token", `{"debug": {"enabled": true}}`); status != http.StatusOK {
+		t.Fatalf("expected the change applied: %v %v", status, response)
+	}
+	expectText(t, h, "(installed,  debugging enabled")
+	if status, response := request(http.MethodPost, path+"/rollback", "Bearer harness-token", `{"version": 99}`); status != http.StatusNotFound {
+		t.Errorf("expected an unknown version not found: %v %v", status, response)
+	}
+	if status, response := request(http.MethodPost, "/api/tenants/missing/rollback", "Bearer harness-token", `{"version": 1}`); status != http.StatusNotFound {
+		t.Errorf("expected an unknown tenant not found: %v %v", status, response)
+	}
+}
+
+func TestHarnessMetrics(t *testing.T) {
+	h := startHarness(t)
+	if err := h.DB.Table(store.DefaultTableName).Where("client_key = ?", gammaKey).Update("context", "{broken").Error; err != nil {
+		t.Fatal(err)
+	}
+	m, err := CollectTenantMetrics("harness", h.Console.currentRepo())
+	if err != nil {
+		t.Fatal(err)
+	} else if m.ParseErrors != 1 || m.Flagged != 0 || m.Installed+m.NotInstalled != 3 {
+		t.Fatalf("expected the flagged tenant counted as a parse error: %+v", m)
+	}
+	var b strings.Builder
+	if err = WriteMetrics(&b, []*TenantMetrics{m}); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(b.String(), `gonnectian_tenants_parse_errors{source="harness"} 1`) {
+		t.Fatalf("expected the parse errors metric:\n%v", b.String())
+	}
+}
+
+func TestHarnessHelpAndPalette(t *testing.T) {
+	h := startHarness(t)
+	h.SendKey(cdk.KeyRune, '?')
+	expectText(t, h, "Open the command palette")
+	expectText(t, h, "Tenants Panel")
+	expectText(t, h, "Sort by the next field")
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, "Open the command palette")
+
+	h.SendKey(cdk.KeyCtrlP, 0)
+	expectText(t, h, "Commands")
+	typeText(h, "reverse sort")
+	expectText(t, h, "Reverse the sort order")
+	expectNoText(t, h, "Select all tenants")
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, "Sort: base url ▼")
+}
+
+func TestHarnessHelpKeys(t *testing.T) {
+	h := startHarness(t)
+	for _, panel := range []cdk.Key{cdk.KeyF2, cdk.KeyF3} {
+		h.SendKey(panel, 0)
+		for _, key := range []cdk.Key{cdk.KeyF1, cdk.KeyRune} {
+			h.SendKey(key, '?')
+			expectText(t, h, "Open the command palette")
+			h.SendKey(cdk.KeyEscape, 0)
+			expectNoText(t, h, "Open the command palette")
+		}
+		// with the focus on a toggle button
+		h.SendKey(cdk.KeyTAB, 0)
+		h.SendKey(cdk.KeyRune, '?')
+		expectText(t, h, "Open the command palette")
+		h.SendKey(cdk.KeyEscape, 0)
+		expectNoText(t, h, "Open the command palette")
+	}
+	expectText(t, h, "App Info <F2>")
+	expectText(t, h, "Tenants <F3>")
+}
+
+func TestHarnessToolbar(t *testing.T) {
+	h := startHarness(t)
+	expectText(t, h, "Sort: base url ▲")
+	h.SendKey(cdk.KeyRune, 's')
+	expectText(t, h, "Sort: created ▲")
+	h.SendKey(cdk.KeyRune, 'S')
+	expectText(t, h, "Sort: created ▼")
+	if p := tenantsPanel(h); p.visible[0].ClientKey != gammaKey {
+		t.Fatalf("expected the newest tenant first: %v", p.visible[0].BaseURL)
+	}
+
+	clickText(t, h, "Show: all")
+	expectText(t, h, "1 of 3 tenants found (flagged)")
+	expectText(t, h, "https://gamma.atlassian.net")
+	clickText(t, h, "Show: flagged")
+	expectText(t, h, "2 of 3 tenants found (unflagged)")
+	expectNoText(t, h, "https://gamma.atlassian.net")
+
+	h.SendKey(cdk.KeyRune, 'v')
+	expectText(t, h, "View: table")
+	clickText(t, h, "License")
+	expectText(t, h, "License ▲")
+	clickText(t, h, "License ▲")
+	expectText(t, h, "License ▼")
+	if p := tenantsPanel(h); p.sort != (TenantSort{Field: SortByLicense, Desc: true}) {
+		t.Fatalf("expected a descending license sort: %v", p.sort)
+	}
+	h.SendKey(cdk.KeyRune, '4')
+	expectNoText(t, h, "License")
+	h.SendKey(cdk.KeyRune, 'v')
+	expectText(t, h, "View: cards")
+
+	clickText(t, h, "Batch (0)")
+	clickText(t, h, "Select All")
+	expectText(t, h, "Batch (2)")
+	clickText(t, h, "Batch (2)")
+	clickText(t, h, "Clear Selection")
+	expectText(t, h, "Batch (0)")
+}
+
+func TestHarnessNavigation(t *testing.T) {
+	h := startHarness(t)
+	p := tenantsPanel(h)
+	expectCurrent := func(key string) {
+		t.Helper()
+		if !h.waitUntil(func() bool { return p.current == key }) {
+			t.Fatalf("expected the cursor on %v, found %v:\n%v", key, p.current, h.ScreenText())
+		}
+	}
+	expectCurrent(alphaKey)
+	h.SendKey(cdk.KeyRune, 'j')
+	expectCurrent(betaKey)
+	h.SendKey(cdk.KeyCtrlN, 0)
+	expectCurrent(gammaKey)
+	h.SendKey(cdk.KeyRune, 'k')
+	expectCurrent(betaKey)
+	h.SendKey(cdk.KeyRune, 'G')
+	expectCurrent(gammaKey)
+	h.SendKey(cdk.KeyRune, 'g')
+	expectCurrent(alphaKey)
+
+	h.SendKey(cdk.KeyRune, '/')
+	expectText(t, h, "Search")
+	typeText(h, "gamma")
+	h.SendKey(cdk.KeyEnter, 0)
+	expectCurrent(gammaKey)
+
+	h.SendKey(cdk.KeyCtrlS, 0)
+	expectText(t, h, "gamma ")
+	for range "gamma" {
+		h.SendKey(cdk.KeyBackspace2, 0)
+	}
+	typeText(h, "nothing-matches")
+	h.SendKey(cdk.KeyEnter, 0)
+	closeDialog(t, h, `No tenants match "nothing-matches"`)
+	expectCurrent(gammaKey)
+}
+
+func TestHarnessNavigationEnter(t *testing.T) {
+	h := startHarness(t)
+	before := make(map[string]TenantContext)
+	for _, clientKey := range []string{alphaKey, betaKey, gammaKey} {
+		before[clientKey] = tenantContext(t, h, clientKey)
+	}
+	h.SendKey(cdk.KeyRune, 'G')
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, `"clientKey": "harness-client-3"`)
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, `"clientKey": "harness-client-3"`)
+	for clientKey, expected := range before {
+		if ctx := tenantContext(t, h, clientKey); !reflect.DeepEqual(ctx, expected) {
+			t.Fatalf("expected enter to change nothing for %v: %v", clientKey, ctx)
+		}
+	}
+
+	// a button the user moved the focus to is pressed, moving the cursor
+	// returns the focus to the list
+	h.SendKey(cdk.KeyTAB, 0)
+	h.SendKey(cdk.KeyRune, 'k')
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, `"clientKey": "harness-client-2"`)
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, `"clientKey": "harness-client-2"`)
+
+	h.SendKey(cdk.KeyRune, 'v')
+	expectText(t, h, "View: table")
+	h.SendKey(cdk.KeyRune, 'g')
+	h.SendKey(cdk.KeyEnter, 0)
+	expectText(t, h, `"clientKey": "harness-client-1"`)
+	if p := tenantsPanel(h); len(p.selected) != 0 {
+		t.Fatalf("expected enter to select nothing: %v", p.selected)
+	}
+}
+
+func TestHarnessMouse(t *testing.T) {
+	h := startHarness(t)
+	clickText(t, h, "[2] https://beta.atlassian.net")
+	if p := tenantsPanel(h); !h.waitUntil(func() bool { return p.current == betaKey }) {
+		t.Fatalf("expected the cursor on %v, found %v", betaKey, p.current)
+	}
+	// a click on another row, so the double click does not pair with the first
+	clickText(t, h, "[1] https://alpha.atlassian.net")
+	doubleClickText(t, h, "[2] https://beta.atlassian.net")
+	expectText(t, h, `"clientKey": "harness-client-2"`)
+	expectNoText(t, h, "secret-2")
+	h.SendKey(cdk.KeyEscape, 0)
+	expectNoText(t, h, `"clientKey"`)
+}
+
+func TestHarnessTheme(t *testing.T) {
+	h := startHarness(t)
+	h.SendKey(cdk.KeyF8, 0)
+	expectText(t, h, "Select the console theme:")
+	expectText(t, h, DefaultThemeName+" (current)")
+	clickText(t, h, "high-contrast")
+	expectNoText(t, h, "Select the console theme:")
+	if theme := h.Console.curses.theme; theme == nil || theme.Name != "high-contrast" {
+		t.Fatalf("expected the high-contrast theme: %v", theme)
+	}
+}
+
+func TestHarnessQuit(t *testing.T) {
+	h := startHarness(t)
+	h.SendKey(cdk.KeyF10, 0)
+	if !h.waitUntil(func() bool { return !h.Display().IsRunning() }) {
+		t.Fatal("expected the display to stop running")
+	}
+}
@@ -0,0 +1,97 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-curses/cdk"
+)
+
+// KeyBinding describes the keys of an action for the help overlay
+type KeyBinding struct {
+	Keys string
+	Help string
+}
+
+// HelpPanel is implemented by panels with their own key bindings, which are
+// listed in the help overlay
+type HelpPanel interface {
+	KeyBindings() []KeyBinding
+}
+
+// HelpKeyBindings are the bindings available in all panels, in addition to
+// the accelerators of the toggle buttons
+var HelpKeyBindings = []KeyBinding{
+	{"F1, ?", "Show this help"},
+	{"Ctrl+P", "Open the command palette"},
+	{"Tab, Shift+Tab", "Move the focus between buttons"},
+	{"Enter, Space", "Activate the focused button"},
+}
+
+// addKeyBinding records an accelerator for the help overlay
+func (c *CCurses) addKeyBinding(accelKey cdk.Key, help string) {
+	keys, ok := cdk.KeyNames[accelKey]
+	if !ok {
+		keys = fmt.Sprintf("%v", accelKey)
+	}
+	c.bindings = append(c.bindings, KeyBinding{Keys: keys, Help: help})
+}
+
+// helpText returns the global key bindings followed by those of each panel
+func (c *CCurses) helpText() (text string) {
+	type section struct {
+		title    string
+		bindings []KeyBinding
+	}
+	sections := []section{{"Global", append(append([]KeyBinding{}, HelpKeyBindings...), c.bindings...)}}
+	for _, key := range c.pOrder {
+		if p, ok := c.panels[key].(HelpPanel); ok {
+			if bindings := p.KeyBindings(); len(bindings) > 0 {
+				sections = append(sections, section{c.panels[key].Name() + " Panel", bindings})
+			}
+		}
+	}
+
+	width := 0
+	for _, s := range sections {
+		for _, b := range s.bindings {
+			if size := utf8.RuneCountInString(b.Keys); size > width {
+				width = size
+			}
+		}
+	}
+
+	var lines []string
+	for idx, s := range sections {
+		if idx > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, s.title)
+		for _, b := range s.bindings {
+			lines = append(lines, fmt.Sprintf("  %v%v  %v", b.Keys, strings.Repeat(" ", width-utf8.RuneCountInString(b.Keys)), b.Help))
+		}
+	}
+	text = strings.Join(lines, "\n")
+	return
+}
+
+func (c *CCurses) showHelp() {
+	c.showText("Help", c.helpText())
+}
@@ -0,0 +1,161 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+// HistoryTableSuffix is appended to the tenant table name for the table of
+// context snapshots
+const HistoryTableSuffix = "_context_history"
+
+// ConsoleContextKeys are the context keys set by the console, a roll back only
+// restores these as the others, such as the license and reject, are maintained
+// by features-gonnectian
+var ConsoleContextKeys = []string{
+	CtxKeyDebug,
+	CtxKeyDebugExpires,
+	CtxKeyAllowedUnlicensed,
+	CtxKeyUnlicensedExpires,
+	CtxKeyUnlicensedReason,
+	CtxKeyFlagged,
+	CtxKeyFlaggedAt,
+}
+
+// ContextSnapshot is a version of a tenant context, recorded by the
+// repositories on each context change made through the console; version 1 is
+// the context as it was before the first change. Each version is unique per
+// ClientKey, see GormTenantRepository.MigrateHistory
+type ContextSnapshot struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClientKey string `gorm:"size:255"`
+	Version   int
+	Context   []byte
+	Note      string
+	CreatedAt time.Time
+}
+
+func (s *ContextSnapshot) TenantContext() (ctx TenantContext, err error) {
+	ctx = make(TenantContext)
+	if len(s.Context) > 0 {
+		if err = json.Unmarshal(s.Context, &ctx); err != nil {
+			err = fmt.Errorf("error parsing context version %d: %v", s.Version, err)
+		}
+	}
+	return
+}
+
+// Label describes the snapshot, with the time it was recorded shown in the
+// given location
+func (s *ContextSnapshot) Label(loc *time.Location) string {
+	return fmt.Sprintf("v%d %v %v", s.Version, s.CreatedAt.In(loc).Format(TimeFormat), s.Note)
+}
+
+// makeSnapshots returns the snapshots to record for a context change, given
+// the latest recorded version; nothing is recorded if the context is
+// unchanged
+func makeSnapshots(tenant *store.Tenant, before, after TenantContext, latest int) (snapshots []*ContextSnapshot, err error) {
+	diff := DiffContexts(before, after)
+	if len(diff) == 0 {
+		return
+	}
+	now := time.Now()
+	var data []byte
+	if latest == 0 {
+		if data, err = json.Marshal(before); err != nil {
+			return
+		}
+		latest += 1
+		snapshots = append(snapshots, &ContextSnapshot{ClientKey: tenant.ClientKey, Version: latest, Context: data, Note: "initial", CreatedAt: now})
+	}
+	if data, err = json.Marshal(after); err != nil {
+		return
+	}
+	snapshots = append(snapshots, &ContextSnapshot{ClientKey: tenant.ClientKey, Version: latest + 1, Context: data, Note: strings.Join(diff, "; "), CreatedAt: now})
+	return
+}
+
+// restoreConsoleKeys returns a copy of the current context with the
+// ConsoleContextKeys of the snapshot context
+func restoreConsoleKeys(current, snapshot TenantContext) (restored TenantContext) {
+	restored = current.Copy()
+	for _, key := range ConsoleContextKeys {
+		if value, ok := snapshot[key]; ok {
+			restored[key] = value
+		} else {
+			delete(restored, key)
+		}
+	}
+	if restored.AllowedUnlicensed() {
+		// as with AllowUnlicensed, features-gonnectian no longer rejects it
+		delete(restored, CtxKeyReject)
+	}
+	return
+}
+
+// keptContextKeys returns the keys which differ between the current and the
+// snapshot context but are not restored by a roll back, being outside of the
+// ConsoleContextKeys
+func keptContextKeys(current, snapshot TenantContext) (keys []string) {
+	for _, key := range contextKeys(current, snapshot) {
+		var console bool
+		for _, ck := range ConsoleContextKeys {
+			if console = key == ck; console {
+				break
+			}
+		}
+		if !console && formatValue(current[key]) != formatValue(snapshot[key]) {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+// RollbackContext restores the ConsoleContextKeys of the tenant context to the
+// given recorded version, the rollback is itself recorded as a new version
+func (f *CConsole) RollbackContext(clientKey string, version int) (diff []string, err error) {
+	err = f.currentRepo().Transaction(func(repo TenantRepository) (err error) {
+		var tenant *store.Tenant
+		var snapshots []*ContextSnapshot
+		if tenant, err = repo.Get(clientKey); err != nil {
+			return
+		} else if snapshots, err = repo.History(clientKey); err != nil {
+			return
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.Version == version {
+				var before, after TenantContext
+				if before, err = ParseTenantContext(tenant); err != nil {
+					return
+				} else if after, err = snapshot.TenantContext(); err != nil {
+					return
+				}
+				after = restoreConsoleKeys(before, after)
+				diff = DiffContexts(before, after)
+				return repo.UpdateContext(tenant, after)
+			}
+		}
+		return fmt.Errorf("%w: %d - %v", ErrVersionNotFound, version, tenant.BaseURL)
+	})
+	return
+}
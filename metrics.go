@@ -0,0 +1,155 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// TenantMetrics are the tenant population counts of a single source
+type TenantMetrics struct {
+	Source string
+
+	Installed    int64
+	NotInstalled int64
+
+	Products map[string]int64
+	Licenses map[string]int64
+
+	Debug      int64
+	Unlicensed int64
+	Flagged    int64
+
+	// ParseErrors counts the tenants with a context which could not be parsed,
+	// these are not included in the product, license and context counts
+	ParseErrors int64
+}
+
+// CollectTenantMetrics counts the tenants of the repository using the same
+// queries and context parsing as the TenantsPanel, tenants with an invalid
+// context are logged and counted as ParseErrors
+func CollectTenantMetrics(source string, repo TenantRepository) (m *TenantMetrics, err error) {
+	m = &TenantMetrics{
+		Source:   source,
+		Products: make(map[string]int64),
+		Licenses: make(map[string]int64),
+	}
+	installed, notInstalled := true, false
+	if m.Installed, err = repo.Count(TenantFilter{Installed: &installed}); err != nil {
+		return
+	} else if m.NotInstalled, err = repo.Count(TenantFilter{Installed: &notInstalled}); err != nil {
+		return
+	}
+	tenants, err := repo.List(TenantQuery{})
+	if err != nil {
+		return
+	}
+	for _, tenant := range tenants {
+		ctx, e := ParseTenantContext(tenant)
+		if e != nil {
+			log.ErrorF("%v: %v - %v", source, tenant.BaseURL, e)
+			m.ParseErrors += 1
+			continue
+		}
+		m.Products[tenant.ProductType] += 1
+		m.Licenses[ctx.License()] += 1
+		if ctx.Debug() {
+			m.Debug += 1
+		}
+		if ctx.AllowedUnlicensed() {
+			m.Unlicensed += 1
+		}
+		if ctx.Flagged() {
+			m.Flagged += 1
+		}
+	}
+	return
+}
+
+// CollectMetrics returns the TenantMetrics of all tenant sources
+func (f *CConsole) CollectMetrics() (metrics []*TenantMetrics, err error) {
+	for _, src := range f.sources {
+		var m *TenantMetrics
+		if m, err = CollectTenantMetrics(src.Label, src.repo); err != nil {
+			return nil, fmt.Errorf("%v: %v", src.Label, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format
+func WriteMetrics(w io.Writer, metrics []*TenantMetrics) (err error) {
+	var b strings.Builder
+	family := func(name, help string, samples func(source string, m *TenantMetrics)) {
+		b.WriteString(fmt.Sprintf("# HELP %v %v\n# TYPE %v gauge\n", name, help, name))
+		for _, m := range metrics {
+			samples(metricLabel("source", m.Source), m)
+		}
+	}
+	sample := func(name string, value int64, labels ...string) {
+		b.WriteString(fmt.Sprintf("%v{%v} %d\n", name, strings.Join(labels, ","), value))
+	}
+	byKey := func(name, label string, counts map[string]int64, source string) {
+		var keys []string
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sample(name, counts[key], source, metricLabel(label, key))
+		}
+	}
+
+	family("gonnectian_tenants", "Number of tenants by installed state.", func(source string, m *TenantMetrics) {
+		sample("gonnectian_tenants", m.Installed, source, metricLabel("installed", "true"))
+		sample("gonnectian_tenants", m.NotInstalled, source, metricLabel("installed", "false"))
+	})
+	family("gonnectian_tenants_by_product", "Number of tenants by product type.", func(source string, m *TenantMetrics) {
+		byKey("gonnectian_tenants_by_product", "product", m.Products, source)
+	})
+	family("gonnectian_tenants_by_license", "Number of tenants by license state.", func(source string, m *TenantMetrics) {
+		byKey("gonnectian_tenants_by_license", "license", m.Licenses, source)
+	})
+	family("gonnectian_tenants_debug_enabled", "Number of tenants with debugging enabled.", func(source string, m *TenantMetrics) {
+		sample("gonnectian_tenants_debug_enabled", m.Debug, source)
+	})
+	family("gonnectian_tenants_unlicensed_allowed", "Number of tenants allowed unlicensed access.", func(source string, m *TenantMetrics) {
+		sample("gonnectian_tenants_unlicensed_allowed", m.Unlicensed, source)
+	})
+	family("gonnectian_tenants_flagged", "Number of tenants flagged by an operator.", func(source string, m *TenantMetrics) {
+		sample("gonnectian_tenants_flagged", m.Flagged, source)
+	})
+	family("gonnectian_tenants_parse_errors", "Number of tenants with a context which could not be parsed.", func(source string, m *TenantMetrics) {
+		sample("gonnectian_tenants_parse_errors", m.ParseErrors, source)
+	})
+
+	_, err = io.WriteString(w, b.String())
+	return
+}
+
+func metricLabel(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return fmt.Sprintf("%v=\"%v\"", name, value)
+}
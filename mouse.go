@@ -0,0 +1,99 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/cdk/lib/ptypes"
+	"github.com/go-curses/ctk"
+)
+
+var (
+	// NoMouseFlag disables mouse capture, leaving the terminal free to select
+	// and paste text as usual
+	NoMouseFlag = &cli.BoolFlag{
+		Name:    "gonnectian-no-mouse",
+		Usage:   "disable mouse capture within the console",
+		EnvVars: []string{"GONNECTIAN_NO_MOUSE"},
+	}
+
+	// DoubleClickInterval is the longest time between two clicks of a double
+	// click
+	DoubleClickInterval = 400 * time.Millisecond
+
+	// WheelScrollLines is the number of lines scrolled per wheel impulse
+	WheelScrollLines = 3
+)
+
+// MouseHandlerPanel is implemented by panels which handle mouse events, such
+// as clicking on rows, while they are the active panel
+type MouseHandlerPanel interface {
+	HandleMouse(evt *cdk.EventMouse) (handled bool)
+}
+
+func (c *CCurses) mouseEventHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	evt, ok := argv[1].(*cdk.EventMouse)
+	if !ok {
+		return cenums.EVENT_PASS
+	}
+	if p, ok := c.panels[c.active].(MouseHandlerPanel); ok && p.HandleMouse(evt) {
+		c.console.Display().RequestDraw()
+		c.console.Display().RequestShow()
+		return cenums.EVENT_STOP
+	}
+	return cenums.EVENT_PASS
+}
+
+// isClick reports if the event is a primary button press
+func isClick(evt *cdk.EventMouse) bool {
+	return evt.IsPressed() && evt.ButtonHas(cdk.Button1)
+}
+
+// scrollWheel scrolls the viewport by WheelScrollLines per wheel impulse over
+// it, in place of the page at a time default
+func scrollWheel(scroll ctk.ScrolledViewport, evt *cdk.EventMouse) (handled bool) {
+	if !evt.IsWheelImpulse() || !scroll.HasPoint(ptypes.NewPoint2I(evt.Position())) {
+		return false
+	}
+	delta := WheelScrollLines
+	switch evt.WheelImpulse() {
+	case cdk.WheelUp:
+		delta = -delta
+	case cdk.WheelDown:
+	default:
+		return false
+	}
+	vertical := scroll.GetVAdjustment()
+	value := vertical.GetValue() + delta
+	if upper := vertical.GetUpper(); value > upper {
+		value = upper
+	}
+	if lower := vertical.GetLower(); value < lower {
+		value = lower
+	}
+	vertical.SetValue(value)
+	scroll.Resize()
+	return true
+}
@@ -0,0 +1,181 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+)
+
+// MaxPaletteResults is the number of matching commands listed at once
+const MaxPaletteResults = 12
+
+// Command is an action listed in the command palette, Keys is the optional
+// shortcut shown alongside the Name
+type Command struct {
+	Name string
+	Keys string
+	Run  func()
+}
+
+func (c Command) Label() string {
+	if c.Keys != "" {
+		return c.Name + " (" + c.Keys + ")"
+	}
+	return c.Name
+}
+
+// FuzzyMatch reports if all the characters of the query appear in order
+// within the text, ignoring case; consecutive characters and those at the
+// start of words score higher
+func FuzzyMatch(query, text string) (score int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	t := []rune(strings.ToLower(text))
+	qdx, last := 0, -2
+	for idx, r := range t {
+		if r != q[qdx] {
+			continue
+		}
+		score += 1
+		if idx == last+1 {
+			score += 5
+		}
+		if idx == 0 || !unicode.IsLetter(t[idx-1]) && !unicode.IsDigit(t[idx-1]) {
+			score += 3
+		}
+		last = idx
+		if qdx++; qdx == len(q) {
+			return score, true
+		}
+	}
+	return 0, false
+}
+
+// FilterCommands returns the commands matching the query, best matches first
+// and otherwise in the order given
+func FilterCommands(commands []Command, query string) (matched []Command) {
+	scores := make(map[int]int)
+	var indexes []int
+	for idx, command := range commands {
+		if score, ok := FuzzyMatch(strings.TrimSpace(query), command.Label()); ok {
+			scores[idx] = score
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	for _, idx := range indexes {
+		matched = append(matched, commands[idx])
+	}
+	return
+}
+
+// addCommand records a command for the palette
+func (c *CCurses) addCommand(command Command) {
+	c.commands = append(c.commands, command)
+}
+
+// paletteCommands returns the commands of the active panel followed by the
+// global commands
+func (c *CCurses) paletteCommands() (commands []Command) {
+	if p, ok := c.panels[c.active]; ok {
+		commands = append(commands, p.Commands()...)
+	}
+	commands = append(commands, c.commands...)
+	commands = append(commands,
+		Command{Name: "Refresh", Run: c.Refresh},
+		Command{Name: "Show the help", Keys: "F1, ?", Run: c.showHelp},
+	)
+	return
+}
+
+// showPalette presents the commands filtered by the text entered, enter runs
+// the first match
+func (c *CCurses) showPalette() {
+	commands := c.paletteCommands()
+
+	d := ctk.NewDialogWithButtons(
+		"Commands", c.window,
+		enums.DialogModal,
+		ctk.StockCancel, enums.ResponseCancel,
+	)
+	d.SetSizeRequest(64, MaxPaletteResults+7)
+
+	entry := ctk.NewEntry("")
+	entry.Show()
+	entry.SetSingleLineMode(true)
+	entry.SetSizeRequest(58, 1)
+	d.GetContentArea().PackStart(entry, false, false, 0)
+	activateEntryOnEnter(d, entry)
+
+	var matched []Command
+	buttons := make([]ctk.Button, MaxPaletteResults)
+	for idx := range buttons {
+		response := enums.ResponseType(idx + 1)
+		buttons[idx] = ctk.NewButtonWithLabel("")
+		buttons[idx].SetSizeRequest(58, 1)
+		buttons[idx].Connect(ctk.SignalActivate, "gonnectian-console-palette-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+			d.Response(response)
+			return cenums.EVENT_STOP
+		})
+		d.GetContentArea().PackStart(buttons[idx], false, false, 0)
+	}
+
+	update := func() {
+		if matched = FilterCommands(commands, entry.GetText()); len(matched) > MaxPaletteResults {
+			matched = matched[:MaxPaletteResults]
+		}
+		for idx, bt := range buttons {
+			if idx < len(matched) {
+				bt.SetLabel(matched[idx].Label())
+				bt.Show()
+			} else {
+				bt.Hide()
+			}
+		}
+		d.Resize()
+		c.console.Display().RequestDraw()
+		c.console.Display().RequestShow()
+	}
+	update()
+	entry.Connect(ctk.SignalChangedText, "gonnectian-console-palette-filter", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		update()
+		return cenums.EVENT_PASS
+	})
+	entry.Connect(ctk.SignalActivate, "gonnectian-console-palette-run", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		if len(matched) > 0 {
+			d.Response(enums.ResponseType(1))
+		}
+		return cenums.EVENT_STOP
+	})
+
+	c.runDialog(d, func(response enums.ResponseType) {
+		if idx := int(response) - 1; idx >= 0 && idx < len(matched) {
+			matched[idx].Run()
+		}
+	})
+	entry.GrabFocus()
+}
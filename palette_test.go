@@ -0,0 +1,85 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, test := range []struct {
+		query, text string
+		matched     bool
+	}{
+		{query: "", text: "Enable Debug", matched: true},
+		{query: "ed", text: "Enable Debug", matched: true},
+		{query: "ENABLE", text: "enable debug", matched: true},
+		{query: "f1", text: "Show the help (F1, ?)", matched: true},
+		{query: "de", text: "Enable Debug", matched: true},
+		{query: "gd", text: "Enable Debug"},
+		{query: "debugs", text: "Enable Debug"},
+		{query: "x", text: ""},
+	} {
+		if _, ok := FuzzyMatch(test.query, test.text); ok != test.matched {
+			t.Errorf("%q in %q: expected %v, found %v", test.query, test.text, test.matched, ok)
+		}
+	}
+
+	// consecutive characters and those at the start of words score higher
+	for _, test := range []struct {
+		query, better, worse string
+	}{
+		{query: "deb", better: "Enable Debug", worse: "Disable the Banner"},
+		{query: "ed", better: "Enable Debug", worse: "Reject Unlicensed"},
+		{query: "lic", better: "Reject Unlicensed", worse: "Roll back the Location"},
+	} {
+		better, _ := FuzzyMatch(test.query, test.better)
+		worse, _ := FuzzyMatch(test.query, test.worse)
+		if better <= worse {
+			t.Errorf("%q: expected %q (%v) to score above %q (%v)", test.query, test.better, better, test.worse, worse)
+		}
+	}
+}
+
+func TestFilterCommands(t *testing.T) {
+	commands := []Command{
+		{Name: "Reject Unlicensed"},
+		{Name: "Disable Debug"},
+		{Name: "Enable Debug"},
+		{Name: "Show the help", Keys: "F1, ?"},
+	}
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: "", expected: []string{"Reject Unlicensed", "Disable Debug", "Enable Debug", "Show the help"}},
+		{query: "  ", expected: []string{"Reject Unlicensed", "Disable Debug", "Enable Debug", "Show the help"}},
+		{query: "debug", expected: []string{"Enable Debug", "Disable Debug"}},
+		{query: "en", expected: []string{"Enable Debug", "Reject Unlicensed"}},
+		{query: "f1", expected: []string{"Show the help"}},
+		{query: "zz"},
+	} {
+		var names []string
+		for _, command := range FilterCommands(commands, test.query) {
+			names = append(names, command.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%q: expected %q, found %q", test.query, test.expected, names)
+		}
+	}
+}
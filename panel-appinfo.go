@@ -18,14 +18,16 @@ package gonnectian
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/go-curses/cdk"
 	cenums "github.com/go-curses/cdk/lib/enums"
 	"github.com/go-curses/ctk"
 	"github.com/go-curses/ctk/lib/enums"
-	"github.com/go-enjin/be/pkg/feature"
 
-	gonnectian "github.com/go-enjin/features-gonnectian"
+	"github.com/go-enjin/be/pkg/log"
 )
 
 var _ Panel = (*AppInfoPanel)(nil)
@@ -76,30 +78,58 @@ func (a *AppInfoPanel) Hide() {
 
 func (a *AppInfoPanel) Refresh() {
 
-	info := make(map[string]string)
-	numVersions := 0
-	for _, f := range feature.FilterTyped[gonnectian.Feature](a.curses.console.Enjin.Features().List()) {
-		url := f.GetPluginInstallationURL()
-		dsc := f.GetPluginDescriptor()
-		if _, ok := info[dsc.Name]; !ok {
-			info[dsc.Name] = ""
-		} else {
-			info[dsc.Name] += "\n"
+	apps, installs, unknown, err := a.curses.console.appInstalls()
+	if err != nil {
+		log.ErrorF("%v", err)
+	}
+
+	var names []string
+	versions := make(map[string][]int)
+	for idx, app := range apps {
+		name := app.Descriptor.Name
+		if _, ok := versions[name]; !ok {
+			names = append(names, name)
+		}
+		versions[name] = append(versions[name], idx)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, name)
+		for _, idx := range versions[name] {
+			lines = append(lines, fmt.Sprintf(" - [%v] %v (%d tenants)", apps[idx].Descriptor.Version, apps[idx].URL, len(installs[idx])))
+			for _, url := range installs[idx] {
+				lines = append(lines, "     "+url)
+			}
 		}
-		info[dsc.Name] += fmt.Sprintf(" - [%v] %v", dsc.Version, url)
-		numVersions += 1
 	}
-	var infoText string
-	for k, v := range info {
-		if infoText != "" {
-			infoText += "\n"
+	if len(apps) > 0 && unknown > 0 {
+		lines = append(lines, fmt.Sprintf("%d tenants with no recorded app version, see the %v record-apps command", unknown, a.curses.console.Tag().Kebab()))
+	}
+
+	width, height := 70, len(lines)
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
 		}
-		infoText += fmt.Sprintf("%v\n%v", k, v)
 	}
-	a.label.SetText(infoText)
-	a.frame.SetLabel(fmt.Sprintf("%d applications, %d total versions", len(info), numVersions))
+	if height < 20 {
+		height = 20
+	}
+	a.label.SetSizeRequest(width, height)
+	a.label.SetText(strings.Join(lines, "\n"))
+	a.frame.SetLabel(fmt.Sprintf("%d applications, %d total versions", len(names), len(apps)))
 }
 
 func (a *AppInfoPanel) Container() ctk.Container {
 	return a.frame
+}
+
+func (a *AppInfoPanel) HandleMouse(evt *cdk.EventMouse) (handled bool) {
+	return scrollWheel(a.scroll, evt)
+}
+
+func (a *AppInfoPanel) Commands() []Command {
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,212 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+var BatchActions = []string{
+	"Enable Debug...",
+	"Disable Debug",
+	"Allow Unlicensed...",
+	"Reject Unlicensed",
+	"Compare",
+	"Copy...",
+	"Export",
+	"Delete",
+}
+
+// BatchSelectionActions change the batch selection, these are listed ahead of
+// the BatchActions
+var BatchSelectionActions = []string{
+	"Select All",
+	"Clear Selection",
+}
+
+func (t *TenantsPanel) initBatchToolbar() {
+	t.batchButton = ctk.NewButtonWithLabel("")
+	t.batchButton.Show()
+	t.batchButton.SetSizeRequest(13, 1)
+	t.batchButton.SetTooltipText("Click to change the batch selection or apply an action to all selected tenants")
+	t.batchButton.SetHasTooltip(true)
+	t.batchButton.Connect(ctk.SignalActivate, "gonnectian-console-batch-toolbar-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		t.batchActionsMenu()
+		return cenums.EVENT_STOP
+	})
+	t.toolbar.PackStart(t.batchButton, false, false, 0)
+	t.updateBatchButton()
+}
+
+func (t *TenantsPanel) selectAll() {
+	for _, tenant := range t.visible {
+		if !t.invalid[tenant.ClientKey] {
+			t.selected[tenant.ClientKey] = true
+		}
+	}
+	t.curses.Refresh()
+}
+
+func (t *TenantsPanel) clearSelection() {
+	t.selected = make(map[string]bool)
+	t.curses.Refresh()
+}
+
+func (t *TenantsPanel) updateBatchButton() {
+	t.batchButton.SetLabel(fmt.Sprintf("Batch (%d)", len(t.selected)))
+}
+
+// makeSelectButton returns the batch selection button of the tenant, the row
+// label of the tenant must be added to t.rows beforehand
+func (t *TenantsPanel) makeSelectButton(tenant *store.Tenant) (bt ctk.Button) {
+	bt = ctk.NewButtonWithLabel("")
+	bt.Show()
+	bt.SetSizeRequest(23, 1)
+	bt.SetHasTooltip(true)
+	update := func() {
+		label, tooltip := "[ ] Select", "Press space to add this tenant to the batch selection"
+		if t.selected[tenant.ClientKey] {
+			label, tooltip = "[x] Selected", "Press space to remove this tenant from the batch selection"
+		}
+		if t.layout == TenantsTableLayout {
+			// just the checkbox within table rows
+			label = label[:3]
+		}
+		bt.SetLabel(label)
+		bt.SetTooltipText(tooltip)
+		t.updateRow(tenant.ClientKey)
+	}
+	update()
+	t.trackCurrent(bt, tenant)
+	bt.Connect(ctk.SignalActivate, "gonnectian-console-select-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		if t.selected[tenant.ClientKey] {
+			delete(t.selected, tenant.ClientKey)
+		} else {
+			t.selected[tenant.ClientKey] = true
+		}
+		update()
+		t.updateBatchButton()
+		t.curses.console.Display().RequestDraw()
+		t.curses.console.Display().RequestShow()
+		return cenums.EVENT_STOP
+	})
+	return
+}
+
+func (t *TenantsPanel) selectedTenants() (tenants []*store.Tenant, err error) {
+	var keys []string
+	for key := range t.selected {
+		keys = append(keys, key)
+	}
+	tenants, err = t.curses.console.findTenants(keys)
+	return
+}
+
+func (t *TenantsPanel) batchActionsMenu() {
+	tenants, err := t.selectedTenants()
+	if err != nil {
+		t.curses.notify("Error", err.Error())
+		return
+	}
+	options := append([]string{}, BatchSelectionActions...)
+	message := "Select the tenants to apply an action to:"
+	if len(tenants) > 0 {
+		options = append(options, BatchActions...)
+		message = fmt.Sprintf("Change the selection or apply to %d selected tenants:", len(tenants))
+	}
+	t.curses.promptChoice("Batch Actions", message, options, func(idx int) {
+		switch options[idx] {
+		case "Select All":
+			t.selectAll()
+		case "Clear Selection":
+			t.clearSelection()
+		case "Enable Debug...":
+			t.promptExpiry("Enable Debug", "Disable debugging automatically after:", DebugExpiryChoices, func(duration time.Duration) {
+				t.batchUpdate(tenants, func(tenant *store.Tenant, ctx TenantContext) { ctx.EnableDebug(duration) })
+			})
+		case "Disable Debug":
+			t.batchUpdate(tenants, func(tenant *store.Tenant, ctx TenantContext) { ctx.DisableDebug() })
+		case "Allow Unlicensed...":
+			t.promptExpiry("Allow Unlicensed", "Revoke the unlicensed grants automatically after:", UnlicensedExpiryChoices, func(duration time.Duration) {
+				t.curses.promptText("Allow Unlicensed", "Reason for the grants:", "", func(reason string) {
+					t.batchUpdate(tenants, func(tenant *store.Tenant, ctx TenantContext) {
+						ctx.AllowUnlicensed(duration, strings.TrimSpace(reason))
+					})
+				})
+			})
+		case "Reject Unlicensed":
+			t.batchUpdate(tenants, func(tenant *store.Tenant, ctx TenantContext) { ctx.RejectUnlicensed() })
+		case "Compare":
+			if len(tenants) != 2 {
+				t.curses.notify("Compare", "Select exactly two tenants to compare")
+			} else if rows, err := CompareTenants(tenants[0], tenants[1], t.curses.console.location); err != nil {
+				t.curses.notify("Compare Failed", err.Error())
+			} else {
+				w, _ := t.curses.console.Display().Screen().Size()
+				t.curses.showText("Compare", FormatComparison(tenants[0], tenants[1], rows, w-8))
+			}
+		case "Copy...":
+			t.curses.promptCopy(tenants)
+		case "Export":
+			t.exportTenants(tenants)
+		case "Delete":
+			t.curses.promptConfirm("Delete Tenants", fmt.Sprintf("Permanently delete %d tenants?", len(tenants)), func() {
+				if result, err := t.curses.console.BatchDelete(tenants); err != nil {
+					log.ErrorF("error deleting tenants: %v", err)
+					t.curses.notify("Delete Failed", fmt.Sprintf("no changes were made:\n%v", err))
+				} else {
+					t.selected = make(map[string]bool)
+					t.curses.Refresh()
+					t.curses.notify("Delete", result.Summary())
+				}
+			})
+		}
+	})
+}
+
+func (t *TenantsPanel) exportTenants(tenants []*store.Tenant) {
+	if path, err := ExportTenantsFile(tenants); err != nil {
+		t.curses.notify("Export Failed", err.Error())
+	} else {
+		t.curses.notify("Export", fmt.Sprintf("%d tenants exported to:\n%v", len(tenants), path))
+	}
+}
+
+func (t *TenantsPanel) batchUpdate(tenants []*store.Tenant, fn func(tenant *store.Tenant, ctx TenantContext)) {
+	if result, err := t.curses.console.BatchUpdate(tenants, fn, false); err != nil {
+		log.ErrorF("error updating tenants: %v", err)
+		t.curses.notify("Batch Failed", fmt.Sprintf("no changes were made:\n%v", err))
+	} else {
+		t.curses.Refresh()
+		t.curses.notify("Batch Complete", result.Summary())
+	}
+}
+
+// SourceChanged clears the selection, client keys are only unique within a
+// single tenant source
+func (t *TenantsPanel) SourceChanged() {
+	t.selected = make(map[string]bool)
+}
@@ -0,0 +1,121 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+func (t *TenantsPanel) Commands() (commands []Command) {
+	if tenant, ok := t.currentTenant(); ok {
+		ctx, err := ParseTenantContext(tenant)
+		if err != nil {
+			log.ErrorF("%v - %v", tenant.BaseURL, err)
+			ctx = nil
+		}
+		commands = append(commands, t.tenantCommands(tenant, ctx)...)
+	}
+
+	commands = append(commands,
+		Command{Name: "Select all tenants", Run: t.selectAll},
+		Command{Name: "Clear the selection", Run: t.clearSelection},
+	)
+	if len(t.selected) > 0 {
+		commands = append(commands, Command{Name: fmt.Sprintf("Batch actions (%d selected)...", len(t.selected)), Run: t.batchActionsMenu})
+		commands = append(commands, Command{Name: fmt.Sprintf("Export the %d selected tenants", len(t.selected)), Run: func() {
+			if tenants, err := t.selectedTenants(); err != nil {
+				t.curses.notify("Error", err.Error())
+			} else {
+				t.exportTenants(tenants)
+			}
+		}})
+	}
+	if len(t.visible) > 0 {
+		visible := t.visible
+		commands = append(commands, Command{Name: fmt.Sprintf("Export the %d listed tenants", len(visible)), Run: func() {
+			t.exportTenants(visible)
+		}})
+	}
+
+	refresh := func(fn func()) func() {
+		return func() {
+			fn()
+			t.curses.Refresh()
+		}
+	}
+	commands = append(commands,
+		Command{Name: "Search tenants...", Keys: "/", Run: t.promptSearch},
+		Command{Name: "Sort by the next field", Keys: "s", Run: refresh(t.cycleSortField)},
+		Command{Name: "Reverse the sort order", Keys: "S", Run: refresh(func() { t.sort.Desc = !t.sort.Desc })},
+		Command{Name: "Switch between the card and table layouts", Keys: "v", Run: refresh(t.toggleLayout)},
+		Command{Name: "Show the next tenant filter", Run: refresh(func() { t.flagFilter = t.flagFilter.Next() })},
+	)
+	return
+}
+
+// tenantCommands returns the actions of the tenant card buttons, a tenant with
+// an invalid (nil) context can only be shown or copied
+func (t *TenantsPanel) tenantCommands(tenant *store.Tenant, ctx TenantContext) (commands []Command) {
+	show := []Command{
+		{Name: "Show details: " + tenant.BaseURL, Run: func() { t.showDetail(tenant) }},
+		{Name: "Copy: " + tenant.BaseURL, Run: func() { t.curses.promptCopy([]*store.Tenant{tenant}) }},
+	}
+	if ctx == nil {
+		return show
+	}
+	action := func(name string, handler func(data []interface{}, argv ...interface{}) cenums.EventFlag) Command {
+		return Command{Name: name + ": " + tenant.BaseURL, Run: func() {
+			handler([]interface{}{tenant, ctx})
+		}}
+	}
+	if ctx.Debug() {
+		commands = append(commands, action("Disable debug", t.toggleDebugHandler))
+	} else {
+		commands = append(commands, action("Enable debug", t.toggleDebugHandler))
+	}
+	if ctx.AllowedUnlicensed() {
+		commands = append(commands, action("Reject unlicensed", t.toggleUnlicensedHandler))
+	} else {
+		commands = append(commands, action("Allow unlicensed", t.toggleUnlicensedHandler))
+	}
+	if ctx.Flagged() {
+		commands = append(commands, action("Unflag", t.toggleFlagHandler))
+	} else {
+		commands = append(commands, action("Flag", t.toggleFlagHandler))
+	}
+	commands = append(commands, show...)
+	commands = append(commands, Command{Name: "History: " + tenant.BaseURL, Run: func() { t.historyMenu(tenant) }})
+	name := "Select"
+	if t.selected[tenant.ClientKey] {
+		name = "Deselect"
+	}
+	commands = append(commands, Command{Name: name + ": " + tenant.BaseURL, Run: func() {
+		if t.selected[tenant.ClientKey] {
+			delete(t.selected, tenant.ClientKey)
+		} else {
+			t.selected[tenant.ClientKey] = true
+		}
+		t.curses.Refresh()
+	}})
+	return
+}
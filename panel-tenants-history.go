@@ -0,0 +1,162 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+// HistoryMenuSize is the number of context versions listed at a time in the
+// history menu
+var HistoryMenuSize = 15
+
+var snapshotActions = []string{
+	"Show context",
+	"Diff with current",
+	"Diff with version...",
+	"Roll back to this version",
+}
+
+func (t *TenantsPanel) historyMenu(tenant *store.Tenant) {
+	snapshots, err := t.curses.console.currentRepo().History(tenant.ClientKey)
+	if errors.Is(err, ErrHistoryDisabled) {
+		t.curses.notify("History", err.Error())
+		return
+	} else if err != nil {
+		t.curses.notify("History Failed", err.Error())
+		return
+	} else if len(snapshots) == 0 {
+		t.curses.notify("History", "No context changes have been recorded for:\n"+tenant.BaseURL+"\nOnly the changes made since the migrate command was run are recorded.")
+		return
+	}
+	message := "Context versions of " + tenant.BaseURL + ":\nA roll back restores only the debug, unlicensed and flag settings."
+	t.chooseSnapshot("History", message, snapshots, nil, func(snapshot *ContextSnapshot) {
+		t.snapshotMenu(tenant, snapshots, snapshot)
+	})
+}
+
+// chooseSnapshot lists the snapshots newest first, excluding the given
+// snapshot, HistoryMenuSize at a time with a last option listing the older
+// versions
+func (t *TenantsPanel) chooseSnapshot(title, message string, snapshots []*ContextSnapshot, exclude *ContextSnapshot, fn func(snapshot *ContextSnapshot)) {
+	var candidates []*ContextSnapshot
+	for idx := len(snapshots) - 1; idx >= 0; idx-- {
+		if snapshots[idx] != exclude {
+			candidates = append(candidates, snapshots[idx])
+		}
+	}
+	var page func(offset int)
+	page = func(offset int) {
+		end := offset + HistoryMenuSize
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		shown := candidates[offset:end]
+		labels := snapshotLabels(shown, t.curses.console.location)
+		if end < len(candidates) {
+			labels = append(labels, fmt.Sprintf("Older versions (%d more)...", len(candidates)-end))
+		}
+		t.curses.promptChoice(title, message, labels, func(idx int) {
+			if idx < len(shown) {
+				fn(shown[idx])
+			} else {
+				page(end)
+			}
+		})
+	}
+	page(0)
+}
+
+func snapshotLabels(snapshots []*ContextSnapshot, loc *time.Location) (labels []string) {
+	for _, snapshot := range snapshots {
+		labels = append(labels, padCell(snapshot.Label(loc), 60))
+	}
+	return
+}
+
+func (t *TenantsPanel) snapshotMenu(tenant *store.Tenant, snapshots []*ContextSnapshot, snapshot *ContextSnapshot) {
+	title := fmt.Sprintf("Version %d", snapshot.Version)
+	t.curses.promptChoice(title, snapshot.Label(t.curses.console.location), snapshotActions, func(idx int) {
+		ctx, err := snapshot.TenantContext()
+		if err != nil {
+			t.curses.notify("History Failed", err.Error())
+			return
+		}
+		switch snapshotActions[idx] {
+		case "Show context":
+			data, _ := json.MarshalIndent(ctx, "", "  ")
+			t.curses.showText(title, string(data))
+
+		case "Diff with current":
+			if current, err := t.curses.console.currentRepo().Get(tenant.ClientKey); err != nil {
+				t.curses.notify("History Failed", err.Error())
+			} else if cc, err := ParseTenantContext(current); err != nil {
+				t.curses.notify("History Failed", err.Error())
+			} else {
+				t.showSnapshotDiff(fmt.Sprintf("v%d -> current", snapshot.Version), ctx, cc)
+			}
+
+		case "Diff with version...":
+			t.chooseSnapshot(title, "Compare with version:", snapshots, snapshot, func(other *ContextSnapshot) {
+				if oc, err := other.TenantContext(); err != nil {
+					t.curses.notify("History Failed", err.Error())
+				} else if other.Version < snapshot.Version {
+					t.showSnapshotDiff(fmt.Sprintf("v%d -> v%d", other.Version, snapshot.Version), oc, ctx)
+				} else {
+					t.showSnapshotDiff(fmt.Sprintf("v%d -> v%d", snapshot.Version, other.Version), ctx, oc)
+				}
+			})
+
+		case "Roll back to this version":
+			message := fmt.Sprintf("Restore the debug, unlicensed and flag settings of %v to version %d?", tenant.BaseURL, snapshot.Version)
+			if current, err := t.curses.console.currentRepo().Get(tenant.ClientKey); err != nil {
+				t.curses.notify("Roll Back Failed", err.Error())
+				return
+			} else if cc, err := ParseTenantContext(current); err != nil {
+				t.curses.notify("Roll Back Failed", err.Error())
+				return
+			} else if kept := keptContextKeys(cc, ctx); len(kept) > 0 {
+				message += "\nThese keys differ and are kept as they are: " + strings.Join(kept, ", ")
+			}
+			t.curses.promptConfirm("Roll Back", message, func() {
+				if diff, err := t.curses.console.RollbackContext(tenant.ClientKey, snapshot.Version); err != nil {
+					log.ErrorF("error rolling back context: %v", err)
+					t.curses.notify("Roll Back Failed", err.Error())
+				} else {
+					t.curses.Refresh()
+					t.curses.notify("Roll Back", fmt.Sprintf("%v restored to version %d, %d changes", tenant.BaseURL, snapshot.Version, len(diff)))
+				}
+			})
+		}
+	})
+}
+
+func (t *TenantsPanel) showSnapshotDiff(title string, before, after TenantContext) {
+	diff := DiffContexts(before, after)
+	if len(diff) == 0 {
+		diff = []string{"(no differences)"}
+	}
+	t.curses.showText(title, strings.Join(diff, "\n"))
+}
@@ -0,0 +1,333 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/cdk/lib/ptypes"
+	"github.com/go-curses/ctk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+const (
+	// cardMinHeight is the number of lines of a tenant card with a short text:
+	// the column of five buttons ending with the Select toggle between the
+	// separator line and a blank line; cards with more lines of text are taller
+	cardMinHeight = 7
+	// cardFrameHeight is the number of lines of a card frame around its text
+	cardFrameHeight = 2
+	// cardButtonWidth is the width of the column of buttons on each card
+	cardButtonWidth = 23
+	// cardMarginWidth is the number of columns of a card frame around its text
+	// and buttons
+	cardMarginWidth = 2
+	// panelChromeHeight is the number of screen lines not available to the
+	// tenant list: window borders and title, frame borders and label, the
+	// toolbar and the toggle buttons
+	panelChromeHeight = 9
+)
+
+func (t *TenantsPanel) handleNavigationKey(evt *cdk.EventKey) (handled bool) {
+	switch {
+	case isCtrlKey(evt, cdk.KeyCtrlN):
+		t.moveCursor(1)
+		return true
+	case isCtrlKey(evt, cdk.KeyCtrlS):
+		t.promptSearch()
+		return true
+	case evt.Rune() == '\n' || evt.Rune() == '\r':
+		// cdk delivers enter as Ctrl+M, match the rune as the dialogs do
+		if !t.listFocused() {
+			return false
+		} else if tenant, ok := t.currentTenant(); ok {
+			t.showDetail(tenant)
+		}
+		return true
+	}
+	switch evt.Key() {
+	case cdk.KeyPgDn:
+		t.moveCursor(t.pageRows(1))
+	case cdk.KeyPgUp:
+		t.moveCursor(-t.pageRows(-1))
+	case cdk.KeyRune:
+		if evt.Modifiers() != cdk.ModNone && evt.Modifiers() != cdk.ModShift {
+			return false
+		}
+		switch evt.Rune() {
+		case 'j':
+			t.moveCursor(1)
+		case 'k':
+			t.moveCursor(-1)
+		case 'g':
+			t.setCursor(0)
+		case 'G':
+			t.setCursor(len(t.visible) - 1)
+		case 'x':
+			t.toggleCursorSelected()
+		case '/':
+			t.promptSearch()
+		case 'n':
+			t.findNext(1)
+		case 'N':
+			t.findNext(-1)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// trackCurrent moves the cursor to the tenant when the button gains the focus
+func (t *TenantsPanel) trackCurrent(bt ctk.Button, tenant *store.Tenant) {
+	bt.Connect(ctk.SignalGainedFocus, "gonnectian-console-current-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		previous := t.current
+		t.current = tenant.ClientKey
+		t.updateRow(previous)
+		t.updateRow(t.current)
+		return cenums.EVENT_PASS
+	})
+}
+
+// currentTenant returns the visible tenant at the cursor
+func (t *TenantsPanel) currentTenant() (tenant *store.Tenant, ok bool) {
+	if idx := t.cursorIndex(); idx >= 0 {
+		return t.visible[idx], true
+	}
+	return nil, false
+}
+
+func (t *TenantsPanel) cursorIndex() int {
+	for idx, tenant := range t.visible {
+		if tenant.ClientKey == t.current {
+			return idx
+		}
+	}
+	return -1
+}
+
+// updateRow sets the theme of the row label for the cursor and selected
+// states of the tenant
+func (t *TenantsPanel) updateRow(clientKey string) {
+	if tl, ok := t.rows[clientKey]; ok {
+		tl.SetTheme(t.curses.rowTheme(clientKey == t.current, t.selected[clientKey]))
+	}
+}
+
+// pageRows returns the number of rows from the cursor, in the direction given,
+// which fit within the lines visible at once
+func (t *TenantsPanel) pageRows(direction int) (rows int) {
+	_, h := t.curses.console.Display().Screen().Size()
+	page := h - panelChromeHeight
+	if t.layout == TenantsTableLayout {
+		page -= 1 // header
+	}
+	used := 0
+	for idx := t.cursorIndex() + direction; idx >= 0 && idx < len(t.visible); idx += direction {
+		top, height := t.rowSpan(idx)
+		if used += height; top < 0 || used > page {
+			break
+		}
+		rows += 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	return
+}
+
+// rowSpan returns the first line of the row at idx within the list and its
+// number of lines, from the offsets of the last refresh
+func (t *TenantsPanel) rowSpan(idx int) (top, height int) {
+	if idx < 0 || idx+1 >= len(t.offsets) {
+		return -1, 0
+	}
+	return t.offsets[idx], t.offsets[idx+1] - t.offsets[idx]
+}
+
+// moveCursor moves the cursor by delta rows, from the first row if there is
+// no cursor yet
+func (t *TenantsPanel) moveCursor(delta int) {
+	idx := t.cursorIndex()
+	if idx < 0 {
+		idx = 0
+	} else {
+		idx += delta
+	}
+	t.setCursor(idx)
+}
+
+// setCursor moves the cursor to the visible row at idx, clamped to the list,
+// returns the focus to the list and scrolls the row into view
+func (t *TenantsPanel) setCursor(idx int) {
+	if len(t.visible) == 0 {
+		return
+	} else if idx < 0 {
+		idx = 0
+	} else if idx >= len(t.visible) {
+		idx = len(t.visible) - 1
+	}
+	previous := t.current
+	t.current = t.visible[idx].ClientKey
+	t.updateRow(previous)
+	t.updateRow(t.current)
+	t.focusList()
+	t.scrollToRow(idx)
+	display := t.curses.console.Display()
+	display.RequestDraw()
+	display.RequestShow()
+}
+
+// focusList moves the focus from any button the user moved it to back to the
+// toggle of this panel, where a refresh leaves it, so that enter acts on the
+// cursor row rather than pressing a button
+func (t *TenantsPanel) focusList() {
+	if toggle, ok := t.curses.toggles[t.Key()]; ok && t.curses.active == t.Key() && !t.listFocused() {
+		toggle.GrabFocus()
+	}
+}
+
+// listFocused reports if the focus is on the toggle of this panel rather than
+// on a button, enter only shows the details of the cursor row while it is
+func (t *TenantsPanel) listFocused() bool {
+	focus := t.curses.window.GetFocus()
+	if focus == nil {
+		return true
+	}
+	toggle, ok := t.curses.toggles[t.Key()]
+	return ok && focus.ObjectID() == toggle.ObjectID()
+}
+
+func (t *TenantsPanel) scrollToRow(idx int) {
+	top, height := t.rowSpan(idx)
+	if top < 0 {
+		return
+	}
+	vertical := t.scroll.GetVAdjustment()
+	page := vertical.GetPageSize()
+	if page <= 0 {
+		page = t.scroll.GetAllocation().H
+	}
+	switch value := vertical.GetValue(); {
+	case idx == 0:
+		vertical.SetValue(0)
+	case top < value || height > page:
+		// a card taller than the page shows from its first line
+		vertical.SetValue(top)
+	case top+height > value+page:
+		vertical.SetValue(top + height - page)
+	default:
+		return
+	}
+	t.scroll.Resize()
+}
+
+func (t *TenantsPanel) toggleCursorSelected() {
+	if tenant, ok := t.currentTenant(); ok && !t.invalid[tenant.ClientKey] {
+		if t.selected[tenant.ClientKey] {
+			delete(t.selected, tenant.ClientKey)
+		} else {
+			t.selected[tenant.ClientKey] = true
+		}
+		t.curses.Refresh()
+	}
+}
+
+func (t *TenantsPanel) promptSearch() {
+	t.curses.promptText("Search", "Find tenants by URL, client key, product or description:", t.search, func(text string) {
+		if t.search = strings.TrimSpace(text); t.search != "" {
+			t.findNext(1)
+		}
+	})
+}
+
+// matchSearch reports if the tenant fields contain the search text, ignoring
+// case
+func (t *TenantsPanel) matchSearch(tenant *store.Tenant) bool {
+	query := strings.ToLower(t.search)
+	for _, field := range []string{tenant.BaseURL, tenant.ClientKey, tenant.ProductType, tenant.Description} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// findNext moves the cursor to the next matching row in the direction given,
+// wrapping around the list
+func (t *TenantsPanel) findNext(direction int) {
+	if t.search == "" {
+		t.promptSearch()
+		return
+	}
+	count := len(t.visible)
+	start := t.cursorIndex()
+	if start < 0 && direction < 0 {
+		start = 0
+	}
+	for step := 1; step <= count; step++ {
+		idx := ((start+direction*step)%count + count) % count
+		if t.matchSearch(t.visible[idx]) {
+			t.setCursor(idx)
+			return
+		}
+	}
+	t.curses.notify("Search", fmt.Sprintf("No tenants match %q", t.search))
+}
+
+// HandleMouse scrolls the list with the wheel and moves the cursor to a row
+// label when clicked, a double click shows the details of the tenant; clicking
+// a table column heading sorts by that column
+func (t *TenantsPanel) HandleMouse(evt *cdk.EventMouse) (handled bool) {
+	if scrollWheel(t.scroll, evt) {
+		return true
+	} else if !isClick(evt) {
+		return false
+	}
+	point := ptypes.NewPoint2I(evt.Position())
+	if t.header != nil && t.header.HasPoint(point) {
+		return t.sortByHeader(point.X - t.header.GetOrigin().X)
+	}
+	for idx, tenant := range t.visible {
+		if tl, ok := t.rows[tenant.ClientKey]; ok && tl.HasPoint(point) {
+			if t.lastClick == tenant.ClientKey && evt.When().Sub(t.lastClickAt) <= DoubleClickInterval {
+				t.lastClick = ""
+				t.showDetail(tenant)
+			} else {
+				t.lastClick, t.lastClickAt = tenant.ClientKey, evt.When()
+				t.setCursor(idx)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// showDetail presents the exported record of the tenant
+func (t *TenantsPanel) showDetail(tenant *store.Tenant) {
+	if text, err := TenantCopyText([]*store.Tenant{tenant}, CopyRecord); err != nil {
+		t.curses.notify("Error", err.Error())
+	} else {
+		t.curses.showText(tenant.BaseURL, text)
+	}
+}
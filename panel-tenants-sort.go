@@ -0,0 +1,81 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+)
+
+// TenantsSortFields are the fields the TenantsPanel can be sorted by, in the
+// order they are cycled through
+var TenantsSortFields = []TenantSortField{
+	SortByBaseURL,
+	SortByCreatedAt,
+	SortByUpdatedAt,
+	SortByInstalled,
+	SortByLicense,
+	SortByProductType,
+}
+
+func (t *TenantsPanel) initSortToolbar() {
+	t.sort = TenantSort{Field: SortByBaseURL}
+	t.sortButton = ctk.NewButtonWithLabel("")
+	t.sortButton.Show()
+	t.sortButton.SetSizeRequest(20, 1)
+	t.sortButton.SetTooltipText("Click to change the sort order (keys: s = next field, S = reverse)")
+	t.sortButton.SetHasTooltip(true)
+	t.sortButton.Connect(ctk.SignalActivate, "gonnectian-console-sort-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		// ascending then descending for each field in turn
+		if t.sort.Desc {
+			t.cycleSortField()
+		} else {
+			t.sort.Desc = true
+		}
+		t.curses.Refresh()
+		return cenums.EVENT_STOP
+	})
+	t.toolbar.PackStart(t.sortButton, false, false, 0)
+	t.updateSortButton()
+}
+
+func (t *TenantsPanel) updateSortButton() {
+	arrow := "▲"
+	if t.sort.Desc {
+		arrow = "▼"
+	}
+	t.sortButton.SetLabel(fmt.Sprintf("Sort: %v %v", t.sort.Field.Label(), arrow))
+}
+
+func (t *TenantsPanel) cycleSortField() {
+	next := 0
+	for idx, field := range TenantsSortFields {
+		if field == t.sort.Field {
+			next = (idx + 1) % len(TenantsSortFields)
+			break
+		}
+	}
+	t.sort = TenantSort{Field: TenantsSortFields[next]}
+}
+
+// sortQuery returns the sort for the repository query, with the client key
+// as a tie-breaker so that the order is always stable
+func (t *TenantsPanel) sortQuery() (sort []TenantSort) {
+	return []TenantSort{t.sort, {Field: SortByClientKey}}
+}
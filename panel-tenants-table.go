@@ -0,0 +1,319 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/ctk"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+type TenantsLayout string
+
+const (
+	TenantsCardLayout  TenantsLayout = "cards"
+	TenantsTableLayout TenantsLayout = "table"
+)
+
+const (
+	TableDateFormat = "2006-01-02"
+
+	// tableMinURLWidth is the narrowest the URL column is squeezed to when
+	// the visible columns do not fit the screen
+	tableMinURLWidth = 16
+	tableSelectWidth = 5
+)
+
+// TenantColumn describes a column of the TenantsPanel table layout, Sort is
+// the field used when the column is sorted and Hidden sets the default
+// visibility; Value is given the apps served, see MatchTenantApp, and the
+// console time zone. Context columns are left blank for a tenant with an
+// invalid context, which is given to Value as nil
+type TenantColumn struct {
+	Key     string
+	Title   string
+	Sort    TenantSortField
+	Hidden  bool
+	Context bool
+	Value   func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string
+}
+
+var TenantColumns = []*TenantColumn{
+	{Key: "url", Title: "URL", Sort: SortByBaseURL, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		return tenant.BaseURL
+	}},
+	{Key: "status", Title: "Status", Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		if ctx == nil {
+			return "invalid"
+		} else if ctx.Flagged() {
+			return "flagged"
+		} else if _, rejected := ctx.Rejected(); rejected {
+			return "rejected"
+		}
+		return ""
+	}},
+	{Key: "product", Title: "Product", Sort: SortByProductType, Hidden: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		return tenant.ProductType
+	}},
+	{Key: "license", Title: "License", Sort: SortByLicense, Context: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		return ctx.License()
+	}},
+	{Key: "installed", Title: "Inst", Sort: SortByInstalled, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		if tenant.AddonInstalled {
+			return "yes"
+		}
+		return "no"
+	}},
+	{Key: "debug", Title: "Debug", Context: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		if !ctx.Debug() {
+			return "off"
+		} else if expires, ok := ctx.DebugExpires(); ok {
+			return "on " + strings.TrimSuffix(FormatRemaining(expires), " left")
+		}
+		return "on"
+	}},
+	{Key: "unlicensed", Title: "Unlic", Context: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		if !ctx.AllowedUnlicensed() {
+			return "no"
+		} else if expires, ok := ctx.UnlicensedExpires(); ok {
+			return "yes " + strings.TrimSuffix(FormatRemaining(expires), " left")
+		}
+		return "yes"
+	}},
+	{Key: "created", Title: "Created", Sort: SortByCreatedAt, Hidden: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		return tenant.CreatedAt.In(loc).Format(TableDateFormat)
+	}},
+	{Key: "updated", Title: "Updated", Sort: SortByUpdatedAt, Hidden: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		return tenant.UpdatedAt.In(loc).Format(TableDateFormat)
+	}},
+	// the number keys toggle the first nine columns, the others are toggled
+	// with the View menu
+	{Key: "app", Title: "App", Context: true, Value: func(tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) string {
+		if idx := MatchTenantApp(apps, ctx); idx >= 0 {
+			return apps[idx].Label()
+		} else if len(apps) > 0 {
+			return "unknown"
+		}
+		return ""
+	}},
+}
+
+// tableHeaderCell is the span of a column within the table heading
+type tableHeaderCell struct {
+	start, end int
+	sort       TenantSortField
+}
+
+func (t *TenantsPanel) initLayoutToolbar() {
+	t.layout = TenantsCardLayout
+	t.hiddenColumns = make(map[string]bool)
+	for _, column := range TenantColumns {
+		t.hiddenColumns[column.Key] = column.Hidden
+	}
+	t.layoutButton = ctk.NewButtonWithLabel("")
+	t.layoutButton.Show()
+	t.layoutButton.SetSizeRequest(13, 1)
+	t.layoutButton.SetTooltipText("Click to change the layout and table columns (keys: v = layout, 1-9 = columns)")
+	t.layoutButton.SetHasTooltip(true)
+	t.layoutButton.Connect(ctk.SignalActivate, "gonnectian-console-layout-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		t.layoutMenu()
+		return cenums.EVENT_STOP
+	})
+	t.toolbar.PackStart(t.layoutButton, false, false, 0)
+	t.updateLayoutButton()
+}
+
+func (t *TenantsPanel) updateLayoutButton() {
+	t.layoutButton.SetLabel(fmt.Sprintf("View: %v", t.layout))
+}
+
+func (t *TenantsPanel) layoutMenu() {
+	options := []string{"Card layout", "Table layout"}
+	for idx, column := range TenantColumns {
+		check := "[x]"
+		if t.hiddenColumns[column.Key] {
+			check = "[ ]"
+		}
+		options = append(options, fmt.Sprintf("%v %d: %v", check, idx+1, column.Title))
+	}
+	t.curses.promptChoice("View", "Select the layout or toggle a table column:", options, func(idx int) {
+		switch idx {
+		case 0:
+			t.layout = TenantsCardLayout
+		case 1:
+			t.layout = TenantsTableLayout
+		default:
+			t.toggleColumn(idx - 2)
+		}
+		t.curses.Refresh()
+	})
+}
+
+func (t *TenantsPanel) toggleLayout() {
+	if t.layout == TenantsTableLayout {
+		t.layout = TenantsCardLayout
+	} else {
+		t.layout = TenantsTableLayout
+	}
+}
+
+// toggleColumn flips the visibility of the column at the given index, the URL
+// column is always shown
+func (t *TenantsPanel) toggleColumn(idx int) (ok bool) {
+	if idx < 0 || idx >= len(TenantColumns) || TenantColumns[idx].Key == "url" {
+		return
+	}
+	key := TenantColumns[idx].Key
+	t.hiddenColumns[key] = !t.hiddenColumns[key]
+	return true
+}
+
+// refreshTable renders one row per tenant, sizing each visible column to its
+// widest value and squeezing the URL column to fit the available width
+func (t *TenantsPanel) refreshTable(width int, tenants []*store.Tenant, contexts []TenantContext) {
+	var columns []*TenantColumn
+	for _, column := range TenantColumns {
+		if !t.hiddenColumns[column.Key] {
+			columns = append(columns, column)
+		}
+	}
+
+	titles := make([]string, len(columns))
+	widths := make([]int, len(columns))
+	values := make([][]string, len(tenants))
+	for cdx, column := range columns {
+		titles[cdx] = column.Title
+		if column.Sort != "" && column.Sort == t.sort.Field {
+			if t.sort.Desc {
+				titles[cdx] += " ▼"
+			} else {
+				titles[cdx] += " ▲"
+			}
+		}
+		widths[cdx] = utf8.RuneCountInString(titles[cdx])
+	}
+	apps := t.curses.console.appDescriptors()
+	for idx, tenant := range tenants {
+		values[idx] = make([]string, len(columns))
+		for cdx, column := range columns {
+			if column.Context && contexts[idx] == nil {
+				continue
+			}
+			values[idx][cdx] = column.Value(tenant, contexts[idx], apps, t.curses.console.location)
+			if size := utf8.RuneCountInString(values[idx][cdx]); size > widths[cdx] {
+				widths[cdx] = size
+			}
+		}
+	}
+
+	available := width - tableSelectWidth - 2
+	used := 0
+	for cdx := range columns {
+		used += widths[cdx] + 2
+	}
+	for cdx, column := range columns {
+		if column.Key == "url" && used > available {
+			if widths[cdx] -= used - available; widths[cdx] < tableMinURLWidth {
+				widths[cdx] = tableMinURLWidth
+			}
+		}
+	}
+
+	formatRow := func(cells []string) string {
+		var parts []string
+		for cdx, cell := range cells {
+			parts = append(parts, padCell(cell, widths[cdx]))
+		}
+		return strings.TrimRight(strings.Join(parts, "  "), " ")
+	}
+
+	header := ctk.NewLabel(strings.Repeat(" ", tableSelectWidth+1) + formatRow(titles))
+	header.Show()
+	header.SetJustify(cenums.JUSTIFY_NONE)
+	header.SetLineWrap(false)
+	header.SetSizeRequest(-1, 1)
+	t.list.PackStart(header, false, false, 0)
+	t.header = header
+	start := tableSelectWidth + 1
+	for cdx, column := range columns {
+		t.headerCells = append(t.headerCells, tableHeaderCell{start: start, end: start + widths[cdx], sort: column.Sort})
+		start += widths[cdx] + 2
+	}
+
+	for idx, tenant := range tenants {
+		row := ctk.NewHBox(false, 1)
+		row.Show()
+		row.SetSizeRequest(-1, 1)
+		t.list.PackStart(row, false, false, 0)
+
+		tl := ctk.NewLabel(formatRow(values[idx]))
+		tl.Show()
+		tl.SetJustify(cenums.JUSTIFY_NONE)
+		tl.SetLineWrap(false)
+		tl.SetSizeRequest(-1, 1)
+
+		t.rows[tenant.ClientKey] = tl
+
+		if contexts[idx] == nil {
+			// read-only, a blank cell in place of the select button
+			blank := ctk.NewLabel("")
+			blank.Show()
+			blank.SetSizeRequest(tableSelectWidth, 1)
+			row.PackStart(blank, false, false, 0)
+			t.updateRow(tenant.ClientKey)
+		} else {
+			bt := t.makeSelectButton(tenant)
+			bt.SetSizeRequest(tableSelectWidth, 1)
+			row.PackStart(bt, false, false, 0)
+		}
+		row.PackStart(tl, true, true, 0)
+	}
+}
+
+// sortByHeader sorts by the column at the offset within the table heading,
+// reversing the order when already sorted by that column
+func (t *TenantsPanel) sortByHeader(x int) (handled bool) {
+	for _, cell := range t.headerCells {
+		if x < cell.start || x >= cell.end || cell.sort == "" {
+			continue
+		}
+		if t.sort.Field == cell.sort {
+			t.sort.Desc = !t.sort.Desc
+		} else {
+			t.sort = TenantSort{Field: cell.sort}
+		}
+		t.curses.Refresh()
+		return true
+	}
+	return false
+}
+
+// padCell left-aligns the text within width cells, truncating with an
+// ellipsis when too long
+func padCell(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
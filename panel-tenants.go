@@ -17,9 +17,10 @@
 package gonnectian
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-curses/cdk"
 	cenums "github.com/go-curses/cdk/lib/enums"
@@ -32,7 +33,10 @@ import (
 	"github.com/go-enjin/be/pkg/log"
 )
 
-var _ Panel = (*TenantsPanel)(nil)
+var (
+	_ Panel           = (*TenantsPanel)(nil)
+	_ KeyHandlerPanel = (*TenantsPanel)(nil)
+)
 
 var (
 	PanelFirstFrameTheme   paint.ThemeName = "panel-frame-theme-first"
@@ -82,12 +86,76 @@ func init() {
 	paint.RegisterTheme(PanelFirstFrameTheme, frameTheme)
 }
 
+// FlagFilter selects the tenants shown by whether they are flagged, see
+// TenantContext.Flagged
+type FlagFilter string
+
+const (
+	ShowAllTenants       FlagFilter = "all"
+	ShowFlaggedTenants   FlagFilter = "flagged"
+	ShowUnflaggedTenants FlagFilter = "unflagged"
+)
+
+func (b FlagFilter) Next() (next FlagFilter) {
+	switch b {
+	case ShowAllTenants:
+		next = ShowFlaggedTenants
+	case ShowFlaggedTenants:
+		next = ShowUnflaggedTenants
+	default:
+		next = ShowAllTenants
+	}
+	return
+}
+
+func (b FlagFilter) Match(ctx TenantContext) (ok bool) {
+	switch b {
+	case ShowFlaggedTenants:
+		ok = ctx.Flagged()
+	case ShowUnflaggedTenants:
+		ok = !ctx.Flagged()
+	default:
+		ok = true
+	}
+	return
+}
+
 type TenantsPanel struct {
 	curses *CCurses
 
-	frame  ctk.Frame
-	scroll ctk.ScrolledViewport
-	list   ctk.VBox
+	frame   ctk.Frame
+	content ctk.VBox
+	toolbar ctk.HBox
+	scroll  ctk.ScrolledViewport
+	list    ctk.VBox
+
+	flagFilter   FlagFilter
+	filterButton ctk.Button
+	batchButton  ctk.Button
+
+	sort       TenantSort
+	sortButton ctk.Button
+
+	layout        TenantsLayout
+	layoutButton  ctk.Button
+	hiddenColumns map[string]bool
+	header        ctk.Label
+	headerCells   []tableHeaderCell
+
+	visible  []*store.Tenant
+	selected map[string]bool
+	// invalid are the ClientKeys of the visible tenants with a context which
+	// fails to parse, these are shown read-only
+	invalid map[string]bool
+	// current is the ClientKey of the cursor row, moved with the navigation
+	// keys or by focusing the buttons of a row
+	current string
+	rows    map[string]ctk.Label
+	offsets []int
+	search  string
+
+	lastClick   string
+	lastClickAt time.Time
 
 	firstFrameTheme   paint.Theme
 	defaultFrameTheme paint.Theme
@@ -97,16 +165,38 @@ type TenantsPanel struct {
 
 func (t *TenantsPanel) Init(c *CCurses) (err error) {
 	t.curses = c
-	t.firstFrameTheme, _ = paint.GetTheme(PanelFirstFrameTheme)
-	t.defaultFrameTheme, _ = paint.GetTheme(PanelDefaultFrameTheme)
+	t.ThemeChanged()
+
+	t.flagFilter = ShowAllTenants
+	t.selected = make(map[string]bool)
 
 	t.frame = ctk.NewFrame("tenants")
 	t.frame.Show()
 
+	t.content = ctk.NewVBox(false, 0)
+	t.content.Show()
+	t.frame.Add(t.content)
+
+	t.toolbar = ctk.NewHBox(false, 1)
+	t.toolbar.Show()
+	t.toolbar.SetSizeRequest(-1, 1)
+	t.content.PackStart(t.toolbar, false, true, 0)
+
+	t.filterButton = ctk.NewButtonWithLabel("")
+	t.filterButton.Show()
+	t.filterButton.SetSizeRequest(17, 1)
+	t.filterButton.SetTooltipText("Click to change which tenants are shown")
+	t.filterButton.SetHasTooltip(true)
+	t.filterButton.Connect(ctk.SignalActivate, "gonnectian-console-filter-handler", t.cycleFilterHandler)
+	t.toolbar.PackStart(t.filterButton, false, false, 0)
+	t.initSortToolbar()
+	t.initLayoutToolbar()
+	t.initBatchToolbar()
+
 	t.scroll = ctk.NewScrolledViewport()
 	t.scroll.Show()
 	t.scroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyNever)
-	t.frame.Add(t.scroll)
+	t.content.PackStart(t.scroll, true, true, 0)
 
 	t.list = ctk.NewVBox(false, 0)
 	t.list.Show()
@@ -114,6 +204,11 @@ func (t *TenantsPanel) Init(c *CCurses) (err error) {
 	return
 }
 
+func (t *TenantsPanel) ThemeChanged() {
+	t.firstFrameTheme, _ = paint.GetTheme(PanelFirstFrameTheme)
+	t.defaultFrameTheme, _ = paint.GetTheme(PanelDefaultFrameTheme)
+}
+
 func (t *TenantsPanel) Key() string {
 	return "tenants"
 }
@@ -137,15 +232,49 @@ func (t *TenantsPanel) Refresh() {
 		t.list.Remove(child)
 		child.Destroy()
 	}
+	t.rows = make(map[string]ctk.Label)
+	t.header, t.headerCells = nil, nil
+
+	t.filterButton.SetLabel(fmt.Sprintf("Show: %v", t.flagFilter))
+	t.updateSortButton()
+	t.updateLayoutButton()
+
+	found, err := t.curses.console.currentRepo().List(TenantQuery{Sort: t.sortQuery()})
+	if err != nil {
+		log.ErrorF("%v", err)
+	}
 
 	var tenants []*store.Tenant
-	t.curses.console.tx().Find(&tenants)
+	var contexts []TenantContext
+	t.invalid = make(map[string]bool)
+	for _, tenant := range found {
+		ctx, err := ParseTenantContext(tenant)
+		if err != nil {
+			// nothing is known of the settings of the tenant, it is listed
+			// with an invalid context badge and no actions
+			log.ErrorF("%v - %v", tenant.BaseURL, err)
+			ctx = nil
+		}
+		if t.flagFilter.Match(ctx) {
+			tenants = append(tenants, tenant)
+			contexts = append(contexts, ctx)
+			if ctx == nil {
+				t.invalid[tenant.ClientKey] = true
+			}
+		}
+	}
 	numTenants := len(tenants)
+	t.visible = tenants
+	t.updateBatchButton()
 
-	t.frame.SetLabel(fmt.Sprintf("%d tenants found:", numTenants))
+	if t.flagFilter == ShowAllTenants {
+		t.frame.SetLabel(fmt.Sprintf("%d tenants found:", numTenants))
+	} else {
+		t.frame.SetLabel(fmt.Sprintf("%d of %d tenants found (%v):", numTenants, len(found), t.flagFilter))
+	}
 
 	if numTenants == 0 {
-		tl := ctk.NewLabel("(no gonnectian installations present")
+		tl := ctk.NewLabel("(no gonnectian installations present)")
 		tl.SetAlignment(0.5, 0.5)
 		tl.SetJustify(cenums.JUSTIFY_CENTER)
 		tl.Show()
@@ -155,39 +284,51 @@ func (t *TenantsPanel) Refresh() {
 
 	w, h := display.Screen().Size()
 	width := w - 2 - 2 - 1 // borders frame-borders scroll
-	height := numTenants * 5
-	if height < h-7 {
+
+	// offsets are the first line of each row within the list, followed by the
+	// height of the list, a card grows with the wrapped lines of its text
+	var apps []AppDescriptor
+	var labels []ctk.Label
+	t.offsets = make([]int, numTenants+1)
+	if t.layout == TenantsTableLayout {
+		t.offsets[0] = 1 // header
+	} else {
+		apps = t.curses.console.appDescriptors()
+	}
+	for idx, tenant := range tenants {
+		height := 1
+		if t.layout != TenantsTableLayout {
+			tl := makeCardLabel(cardText(idx, tenant, contexts[idx], apps, t.curses.console.location))
+			labels = append(labels, tl)
+			// measured at the narrower width of a list which scrolls
+			height = cardHeight(tl, width-1)
+		}
+		t.offsets[idx+1] = t.offsets[idx] + height
+	}
+
+	height := t.offsets[numTenants]
+	if height < h-8 {
 		width += 1
 	} else {
 		width -= 1
 	}
 	t.list.SetSizeRequest(width, height)
 
-	for idx, tenant := range tenants {
-		var ctx map[string]interface{}
-		contextJson := tenant.Context.String()
-		if contextJson == "" {
-			contextJson = `{"debug":"false"}`
-		}
-		if err := json.Unmarshal([]byte(contextJson), &ctx); err != nil {
-			log.ErrorF("error parsing tenant context: %v", err)
-		}
+	if t.layout == TenantsTableLayout {
+		t.refreshTable(width, tenants, contexts)
+		return
+	}
 
-		var debug string
-		if v, ok := ctx["debug"].(string); ok {
-			debug = v
-		} else {
-			debug = "false"
-		}
-		var allowedUnlicensed bool
-		if v, ok := ctx["allowed-unlicensed"].(bool); ok {
-			allowedUnlicensed = v
-		}
+	for idx, tenant := range tenants {
+		ctx := contexts[idx]
+		debug := ctx.Debug()
+		allowedUnlicensed := ctx.AllowedUnlicensed()
+		height := t.offsets[idx+1] - t.offsets[idx]
 
 		frame := ctk.NewFrame("")
 		frame.Show()
 		frame.SetLabelAlign(0.0, 0.5)
-		frame.SetSizeRequest(-1, 5)
+		frame.SetSizeRequest(-1, height)
 		if idx == 0 {
 			frame.SetTheme(t.firstFrameTheme)
 		} else {
@@ -197,34 +338,19 @@ func (t *TenantsPanel) Refresh() {
 
 		hbox := ctk.NewHBox(false, 1)
 		hbox.Show()
-		hbox.SetSizeRequest(-1, 4)
+		hbox.SetSizeRequest(-1, height-1)
 		frame.Add(hbox)
 
-		tenantText := fmt.Sprintf("[%d] %v (lic=%v)", idx+1, tenant.BaseURL, ctx["license"])
-		tenantText += fmt.Sprintf("\n (c=%v / u=%v)", tenant.CreatedAt.Format("2006-01-02 15:04 MST"), tenant.UpdatedAt.Format("2006-01-02 15:04 MST"))
-		if tenant.AddonInstalled {
-			tenantText += "\n  (installed, "
-		} else {
-			tenantText += "\n  (not installed, "
-		}
-		if allowedUnlicensed {
-			tenantText += " allowed unlicensed, "
-		}
-		if debug == "true" {
-			tenantText += " debugging enabled)"
-		} else {
-			tenantText += " debugging disabled)"
-		}
-
-		tl := ctk.NewLabel(tenantText)
-		tl.Show()
-		tl.SetJustify(cenums.JUSTIFY_LEFT)
-		tl.SetSingleLineMode(false)
-		tl.SetLineWrap(false)
-		tl.SetLineWrapMode(cenums.WRAP_NONE)
-		tl.SetSizeRequest(-1, 4) // toggle-width box-child-space
+		tl := labels[idx]
+		tl.SetSizeRequest(-1, height-1) // toggle-width box-child-space
 		hbox.PackStart(tl, true, true, 0)
 
+		if ctx == nil {
+			t.rows[tenant.ClientKey] = tl
+			t.updateRow(tenant.ClientKey)
+			continue
+		}
+
 		vbox := ctk.NewVBox(false, 0)
 		vbox.Show()
 		hbox.PackEnd(vbox, false, true, 0)
@@ -232,20 +358,21 @@ func (t *TenantsPanel) Refresh() {
 		makeButton := func(buttonLabel, tooltipText, key string, handler cdk.SignalListenerFn) {
 			bt := ctk.NewButtonWithLabel(buttonLabel)
 			bt.Show()
-			bt.SetSizeRequest(23, 1)
+			bt.SetSizeRequest(cardButtonWidth, 1)
 			bt.SetTooltipText(tooltipText)
 			bt.SetHasTooltip(true)
 			bt.Connect(ctk.SignalActivate, "gonnectian-console-"+key+"-handler", handler, tenant, ctx)
+			t.trackCurrent(bt, tenant)
 			vbox.PackStart(bt, false, false, 0)
 		}
 
 		var buttonLabel, tooltipText string
-		if debug == "true" {
+		if debug {
 			buttonLabel = "Disable Debug"
 			tooltipText = "Click to disable per-tenant UI debugging"
 		} else {
 			buttonLabel = "Enable Debug"
-			tooltipText = "Click to enable per-tenant UI debugging"
+			tooltipText = "Click to enable per-tenant UI debugging for a limited time"
 		}
 		makeButton(buttonLabel, tooltipText, "debug", t.toggleDebugHandler)
 
@@ -254,71 +381,338 @@ func (t *TenantsPanel) Refresh() {
 			tooltipText = "Click to reject unlicensed installations for this tenant"
 		} else {
 			buttonLabel = "Allow Unlicensed"
-			tooltipText = "Click to allow unlicensed installations for this tenant"
+			tooltipText = "Click to grant unlicensed access for this tenant"
 		}
 		makeButton(buttonLabel, tooltipText, "unlicensed", t.toggleUnlicensedHandler)
+
+		if ctx.Flagged() {
+			buttonLabel = "Unflag Tenant"
+			tooltipText = "Click to clear the flag on this tenant"
+		} else {
+			buttonLabel = "Flag Tenant"
+			tooltipText = "Click to flag this tenant with a note, requests are not refused"
+		}
+		makeButton(buttonLabel, tooltipText, "flag", t.toggleFlagHandler)
+
+		makeButton("More...", "Click to copy fields or browse the context history of this tenant", "more", t.moreHandler)
+
+		t.rows[tenant.ClientKey] = tl
+		vbox.PackStart(t.makeSelectButton(tenant), false, false, 0)
+	}
+
+}
+
+// cardText returns the text of the tenant card, the row number, URL, times
+// and settings of the tenant followed by any notes; a nil ctx is a context
+// which failed to parse, of which no settings are shown
+func cardText(idx int, tenant *store.Tenant, ctx TenantContext, apps []AppDescriptor, loc *time.Location) (tenantText string) {
+	if ctx == nil {
+		tenantText = fmt.Sprintf("[%d] %v [INVALID CONTEXT]", idx+1, tenant.BaseURL)
+		tenantText += fmt.Sprintf("\n (c=%v / u=%v)", tenant.CreatedAt.In(loc).Format(TimeFormat), tenant.UpdatedAt.In(loc).Format(TimeFormat))
+		tenantText += "\n  (the context is not valid JSON, the tenant is read-only)"
+		return
+	}
+	debug := ctx.Debug()
+	allowedUnlicensed := ctx.AllowedUnlicensed()
+	tenantText = fmt.Sprintf("[%d] %v (lic=%v)", idx+1, tenant.BaseURL, ctx.License())
+	if ctx.Flagged() {
+		tenantText += " [FLAGGED]"
+	} else if _, rejected := ctx.Rejected(); rejected {
+		tenantText += " [REJECTED]"
+	}
+	tenantText += fmt.Sprintf("\n (c=%v / u=%v)", tenant.CreatedAt.In(loc).Format(TimeFormat), tenant.UpdatedAt.In(loc).Format(TimeFormat))
+	if tenant.AddonInstalled {
+		tenantText += "\n  (installed, "
+	} else {
+		tenantText += "\n  (not installed, "
+	}
+	if allowedUnlicensed {
+		if expires, ok := ctx.UnlicensedExpires(); ok {
+			tenantText += fmt.Sprintf(" allowed unlicensed %v, ", FormatRemaining(expires))
+		} else {
+			tenantText += " allowed unlicensed, "
+		}
+	}
+	if debug {
+		if expires, ok := ctx.DebugExpires(); ok {
+			tenantText += fmt.Sprintf(" debugging enabled, %v)", FormatRemaining(expires))
+		} else {
+			tenantText += " debugging enabled)"
+		}
+	} else {
+		tenantText += " debugging disabled)"
+	}
+
+	var notes []string
+	if len(apps) > 0 {
+		if appIdx := MatchTenantApp(apps, ctx); appIdx >= 0 {
+			notes = append(notes, "app: "+apps[appIdx].Label())
+		} else {
+			notes = append(notes, "app: unknown")
+		}
+	}
+	if reason := ctx.FlagReason(); reason != "" {
+		if at, ok := ctx.FlaggedAt(); ok {
+			notes = append(notes, fmt.Sprintf("flagged %v: %v", at.In(loc).Format(TimeFormat), reason))
+		} else {
+			notes = append(notes, fmt.Sprintf("flagged: %v", reason))
+		}
+	} else if reason, rejected := ctx.Rejected(); rejected {
+		notes = append(notes, fmt.Sprintf("rejected: %v", reason))
+	}
+	if reason := ctx.UnlicensedReason(); reason != "" {
+		notes = append(notes, fmt.Sprintf("unlicensed grant: %v", reason))
+	}
+	if len(notes) > 0 {
+		tenantText += fmt.Sprintf("\n  (%v)", strings.Join(notes, "; "))
 	}
+	return
+}
+
+func makeCardLabel(text string) (tl ctk.Label) {
+	tl = ctk.NewLabel(text)
+	tl.Show()
+	tl.SetJustify(cenums.JUSTIFY_LEFT)
+	tl.SetSingleLineMode(false)
+	tl.SetLineWrap(true)
+	tl.SetLineWrapMode(cenums.WRAP_WORD)
+	return
+}
 
+// cardHeight returns the number of lines of a card showing the label within a
+// list of the given width, at least cardMinHeight
+func cardHeight(tl ctk.Label, width int) (height int) {
+	_, lines := tl.GetPlainTextInfoAtWidth(width - cardMarginWidth - cardButtonWidth - 1) // box spacing
+	if height = lines + cardFrameHeight; height < cardMinHeight {
+		height = cardMinHeight
+	}
+	return
 }
 
 func (t *TenantsPanel) Container() ctk.Container {
 	return t.frame
 }
 
-func (t *TenantsPanel) toggleDebugHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+func (t *TenantsPanel) HandleKey(evt *cdk.EventKey) (handled bool) {
+	if t.handleNavigationKey(evt) {
+		return true
+	} else if evt.Key() != cdk.KeyRune || evt.Modifiers() != cdk.ModNone && evt.Modifiers() != cdk.ModShift {
+		return
+	}
+	switch r := evt.Rune(); {
+	case r == 's':
+		t.cycleSortField()
+	case r == 'S':
+		t.sort.Desc = !t.sort.Desc
+	case r == 'v':
+		t.toggleLayout()
+	case r >= '1' && r <= '9' && t.layout == TenantsTableLayout:
+		if !t.toggleColumn(int(r - '1')) {
+			return
+		}
+	default:
+		return
+	}
+	t.curses.Refresh()
+	return true
+}
+
+func (t *TenantsPanel) KeyBindings() []KeyBinding {
+	return []KeyBinding{
+		{"s", "Sort by the next field"},
+		{"S", "Reverse the sort order"},
+		{"v", "Switch between the card and table layouts"},
+		{"1-9", "Show or hide a table column"},
+		{"j, Ctrl+N", "Move the cursor down a row"},
+		{"k", "Move the cursor up a row"},
+		{"g, G", "Move the cursor to the first or last row"},
+		{"PgUp, PgDn", "Move the cursor up or down a page"},
+		{"x", "Select or deselect the cursor row"},
+		{"Enter", "Show the details of the cursor row"},
+		{"/, Ctrl+S", "Search the URL, client key, product and description"},
+		{"n, N", "Move the cursor to the next or previous match"},
+		{"More...", "Copy fields or browse the context history of the row"},
+		{"Click", "Move the cursor to the row"},
+		{"Double click", "Show the details of the row"},
+		{"Click heading", "Sort the table by the column, again to reverse"},
+		{"Wheel", "Scroll the list"},
+	}
+}
+
+func (t *TenantsPanel) cycleFilterHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	t.flagFilter = t.flagFilter.Next()
+	t.curses.Refresh()
+	return cenums.EVENT_STOP
+}
+
+func (t *TenantsPanel) saveContext(tenant *store.Tenant, ctx TenantContext) {
+	if err := t.curses.console.currentRepo().UpdateContext(tenant, ctx); err != nil {
+		log.ErrorF("%v", err)
+	}
+	t.curses.Refresh()
+}
+
+func (t *TenantsPanel) parseHandlerData(data []interface{}) (tenant *store.Tenant, ctx TenantContext, ok bool) {
 	if len(data) == 2 {
-		if tenant, ok := data[0].(*store.Tenant); ok {
-			if c, ok := data[1].(map[string]interface{}); ok {
-				if v, ok := c["debug"]; ok {
-					if v == "true" {
-						c["debug"] = "false"
-					} else {
-						c["debug"] = "true"
-					}
-				} else {
-					c["debug"] = "true"
-				}
-				if b, err := json.Marshal(c); err != nil {
-					log.ErrorF("error encoding tenant context change: %v", err)
-				} else {
-					tenant.Context = b
-					if err := t.curses.console.tx().Save(&tenant).Error; err != nil {
-						log.ErrorF("error saving tenant database change: %v", err)
-					}
-					t.curses.Refresh()
-				}
+		if tenant, ok = data[0].(*store.Tenant); ok {
+			ctx, ok = data[1].(TenantContext)
+		}
+	}
+	return
+}
+
+// ExpiryChoice is a preset duration offered when enabling a time-limited
+// tenant setting, a negative Duration prompts for a custom value and zero
+// means no expiry
+type ExpiryChoice struct {
+	Label    string
+	Duration time.Duration
+}
+
+var DebugExpiryChoices = []ExpiryChoice{
+	{"1 hour", time.Hour},
+	{"24 hours", 24 * time.Hour},
+	{"7 days", 7 * 24 * time.Hour},
+	{"Custom...", -1},
+	{"No expiry", 0},
+}
+
+var UnlicensedExpiryChoices = []ExpiryChoice{
+	{"14 days", 14 * 24 * time.Hour},
+	{"30 days", 30 * 24 * time.Hour},
+	{"90 days", 90 * 24 * time.Hour},
+	{"Custom...", -1},
+	{"No expiry", 0},
+}
+
+func (t *TenantsPanel) promptExpiry(title, message string, choices []ExpiryChoice, fn func(duration time.Duration)) {
+	var options []string
+	for _, choice := range choices {
+		options = append(options, choice.Label)
+	}
+	t.curses.promptChoice(title, message, options, func(idx int) {
+		if choice := choices[idx]; choice.Duration >= 0 {
+			fn(choice.Duration)
+			return
+		}
+		t.curses.promptText(title, "Expires after (eg: 90m, 12h, 3d or 2006-01-02):", "", func(text string) {
+			if duration, err := ParseExpiry(text); err != nil {
+				t.curses.notify("Error", err.Error())
+			} else {
+				fn(duration)
 			}
+		})
+	})
+}
+
+func (t *TenantsPanel) toggleDebugHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if tenant, ctx, ok := t.parseHandlerData(data); ok {
+		if ctx.Debug() {
+			ctx.DisableDebug()
+			t.saveContext(tenant, ctx)
+			return cenums.EVENT_STOP
 		}
+		t.promptExpiry("Enable Debug", "Disable debugging automatically after:", DebugExpiryChoices, func(duration time.Duration) {
+			ctx.EnableDebug(duration)
+			t.saveContext(tenant, ctx)
+		})
 	}
 	return cenums.EVENT_STOP
 }
 
 func (t *TenantsPanel) toggleUnlicensedHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
-	if len(data) == 2 {
-		if tenant, ok := data[0].(*store.Tenant); ok {
-			if c, ok := data[1].(map[string]interface{}); ok {
-				if v, ok := c["allowed-unlicensed"].(bool); ok {
-					if v {
-						c["allowed-unlicensed"] = false
-					} else {
-						c["allowed-unlicensed"] = true
-						delete(c, "reject")
-					}
-				} else {
-					c["allowed-unlicensed"] = true
-					delete(c, "reject")
-				}
-				if b, err := json.Marshal(c); err != nil {
-					log.ErrorF("error encoding tenant context change: %v", err)
-				} else {
-					tenant.Context = b
-					if err := t.curses.console.tx().Save(&tenant).Error; err != nil {
-						log.ErrorF("error saving tenant database change: %v", err)
-					}
-					t.curses.Refresh()
-				}
+	if tenant, ctx, ok := t.parseHandlerData(data); ok {
+		if ctx.AllowedUnlicensed() {
+			ctx.RejectUnlicensed()
+			t.saveContext(tenant, ctx)
+			return cenums.EVENT_STOP
+		}
+		t.promptExpiry("Allow Unlicensed", "Revoke the unlicensed grant automatically after:", UnlicensedExpiryChoices, func(duration time.Duration) {
+			t.curses.promptText("Allow Unlicensed", "Reason for the grant (eg: sales evaluation extension):", "", func(reason string) {
+				ctx.AllowUnlicensed(duration, strings.TrimSpace(reason))
+				t.saveContext(tenant, ctx)
+			})
+		})
+	}
+	return cenums.EVENT_STOP
+}
+
+var tenantMoreActions = []string{
+	"Copy...",
+	"History...",
+}
+
+func (t *TenantsPanel) moreHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if tenant, _, ok := t.parseHandlerData(data); ok {
+		t.curses.promptChoice("More", tenant.BaseURL, tenantMoreActions, func(idx int) {
+			switch tenantMoreActions[idx] {
+			case "Copy...":
+				t.curses.promptCopy([]*store.Tenant{tenant})
+			case "History...":
+				t.historyMenu(tenant)
 			}
+		})
+	}
+	return cenums.EVENT_STOP
+}
+
+func (t *TenantsPanel) toggleFlagHandler(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if tenant, ctx, ok := t.parseHandlerData(data); ok {
+		if ctx.Flagged() {
+			ctx.Unflag()
+			t.saveContext(tenant, ctx)
+			return cenums.EVENT_STOP
 		}
+		t.curses.promptText("Flag Tenant", "Note on why "+tenant.BaseURL+" is flagged:", "", func(reason string) {
+			ctx.Flag(strings.TrimSpace(reason))
+			t.saveContext(tenant, ctx)
+		})
 	}
 	return cenums.EVENT_STOP
-}
\ No newline at end of file
+}
+
+func (t *TenantsPanel) LoadPreferences(prefs *Preferences) {
+	p := prefs.Tenants
+	if p == nil {
+		return
+	}
+	switch p.Filter {
+	case ShowAllTenants, ShowFlaggedTenants, ShowUnflaggedTenants:
+		t.flagFilter = p.Filter
+	}
+	for _, field := range TenantsSortFields {
+		if field == p.Sort {
+			t.sort = TenantSort{Field: p.Sort, Desc: p.SortDesc}
+		}
+	}
+	switch p.Layout {
+	case TenantsCardLayout, TenantsTableLayout:
+		t.layout = p.Layout
+	}
+	if p.HiddenColumns != nil {
+		hidden := make(map[string]bool)
+		for _, key := range p.HiddenColumns {
+			hidden[key] = true
+		}
+		for _, column := range TenantColumns {
+			// the url column is never hidden
+			t.hiddenColumns[column.Key] = hidden[column.Key] && column.Key != "url"
+		}
+	}
+}
+
+func (t *TenantsPanel) SavePreferences(prefs *Preferences) {
+	p := &TenantsPreferences{
+		Filter:        t.flagFilter,
+		Sort:          t.sort.Field,
+		SortDesc:      t.sort.Desc,
+		Layout:        t.layout,
+		HiddenColumns: make([]string, 0),
+	}
+	for _, column := range TenantColumns {
+		if t.hiddenColumns[column.Key] {
+			p.HiddenColumns = append(p.HiddenColumns, column.Key)
+		}
+	}
+	prefs.Tenants = p
+}
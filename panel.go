@@ -27,4 +27,8 @@ type Panel interface {
 	Hide()
 	Refresh()
 	Container() ctk.Container
+
+	// Commands returns the actions of the panel listed in the command palette
+	// while the panel is active
+	Commands() []Command
 }
\ No newline at end of file
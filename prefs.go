@@ -0,0 +1,192 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-enjin/be/pkg/globals"
+	"github.com/go-enjin/be/pkg/log"
+)
+
+const (
+	// PreferencesFileName is the name of the preferences file within the
+	// enjin's XDG config directory
+	PreferencesFileName = "gonnectian.json"
+
+	// DefaultPreferencesKey is the preferences key of consoles run without a
+	// --prefix
+	DefaultPreferencesKey = "default"
+)
+
+var (
+	// PreferencesFlag overrides the path of the preferences file, "-" disables
+	// loading and saving preferences
+	PreferencesFlag = &cli.StringFlag{
+		Name:    "gonnectian-preferences",
+		Usage:   "console preferences file, defaults to $XDG_CONFIG_HOME/<enjin>/" + PreferencesFileName + ` and "-" disables preferences`,
+		EnvVars: []string{"GONNECTIAN_PREFERENCES"},
+	}
+)
+
+// PreferencesFile holds the Preferences of each console, keyed by the
+// --prefix the console is run with
+type PreferencesFile struct {
+	Consoles map[string]*Preferences `json:"consoles"`
+}
+
+// Preferences are the per-user console settings saved at Shutdown and
+// restored at Startup
+type Preferences struct {
+	Panel   string              `json:"panel,omitempty"`
+	Theme   string              `json:"theme,omitempty"`
+	Tenants *TenantsPreferences `json:"tenants,omitempty"`
+}
+
+type TenantsPreferences struct {
+	Filter   FlagFilter      `json:"filter,omitempty"`
+	Sort     TenantSortField `json:"sort,omitempty"`
+	SortDesc bool            `json:"sortDesc,omitempty"`
+	Layout   TenantsLayout   `json:"layout,omitempty"`
+	// HiddenColumns are the TenantColumn keys hidden in the table layout, nil
+	// keeps the defaults
+	HiddenColumns []string `json:"hiddenColumns"`
+}
+
+// PreferencesPanel is implemented by panels with settings kept between
+// sessions
+type PreferencesPanel interface {
+	LoadPreferences(prefs *Preferences)
+	SavePreferences(prefs *Preferences)
+}
+
+// DefaultPreferencesPath returns the PreferencesFileName within the enjin's
+// directory of the user config directory, $XDG_CONFIG_HOME on linux
+func DefaultPreferencesPath() (path string, err error) {
+	var dir string
+	if dir, err = os.UserConfigDir(); err == nil {
+		path = filepath.Join(dir, globals.BinName, PreferencesFileName)
+	}
+	return
+}
+
+func preferencesKey(prefix string) string {
+	if prefix == "" {
+		return DefaultPreferencesKey
+	}
+	return prefix
+}
+
+func readPreferencesFile(path string) (pf *PreferencesFile, err error) {
+	pf = &PreferencesFile{}
+	var data []byte
+	if data, err = os.ReadFile(path); errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	} else if err == nil {
+		if err = json.Unmarshal(data, pf); err != nil {
+			err = fmt.Errorf("error parsing preferences %v: %v", path, err)
+		}
+	}
+	if pf.Consoles == nil {
+		pf.Consoles = make(map[string]*Preferences)
+	}
+	return
+}
+
+// ReadPreferences returns the preferences of the console prefix, a missing
+// file or console returns empty preferences
+func ReadPreferences(path, prefix string) (prefs *Preferences, err error) {
+	prefs = &Preferences{}
+	var pf *PreferencesFile
+	if pf, err = readPreferencesFile(path); err == nil {
+		if found, ok := pf.Consoles[preferencesKey(prefix)]; ok && found != nil {
+			prefs = found
+		}
+	}
+	return
+}
+
+// WritePreferences updates the preferences of the console prefix, keeping
+// those of other consoles
+func WritePreferences(path, prefix string, prefs *Preferences) (err error) {
+	var pf *PreferencesFile
+	if pf, err = readPreferencesFile(path); err != nil {
+		return
+	}
+	pf.Consoles[preferencesKey(prefix)] = prefs
+	var data []byte
+	if data, err = json.MarshalIndent(pf, "", "  "); err != nil {
+		return
+	} else if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	// replace the file only once fully written
+	temp := path + ".tmp"
+	if err = os.WriteFile(temp, append(data, '\n'), 0600); err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		// never leave a partial file behind
+		_ = os.Remove(temp)
+	}
+	return
+}
+
+// loadPreferences reads the preferences and applies them to the panels,
+// errors are logged and leave the defaults in place
+func (f *CConsole) loadPreferences() {
+	f.prefs = &Preferences{}
+	if f.prefsPath == "" {
+		return
+	}
+	var err error
+	if f.prefs, err = ReadPreferences(f.prefsPath, f.prefix); err != nil {
+		log.ErrorF("error reading preferences: %v", err)
+		return
+	}
+	for _, panel := range f.curses.panels {
+		if p, ok := panel.(PreferencesPanel); ok {
+			p.LoadPreferences(f.prefs)
+		}
+	}
+	if _, ok := f.curses.panels[f.prefs.Panel]; ok {
+		f.curses.active = f.prefs.Panel
+	}
+}
+
+// savePreferences updates the preferences from the panels and writes them
+func (f *CConsole) savePreferences() {
+	if f.prefsPath == "" || f.curses == nil {
+		return
+	}
+	f.prefs.Panel = f.curses.active
+	for _, panel := range f.curses.panels {
+		if p, ok := panel.(PreferencesPanel); ok {
+			p.SavePreferences(f.prefs)
+		}
+	}
+	if err := WritePreferences(f.prefsPath, f.prefix, f.prefs); err != nil {
+		log.ErrorF("error writing preferences: %v", err)
+	}
+}
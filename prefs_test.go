@@ -0,0 +1,46 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPreferencesPerPrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gonnectian", "preferences.json")
+	saved := map[string]*Preferences{
+		"":    {Panel: "tenants", Theme: "monochrome"},
+		"dev": {Panel: "appinfo", Tenants: &TenantsPreferences{Filter: "flagged", SortDesc: true}},
+	}
+	for prefix, prefs := range saved {
+		if err := WritePreferences(path, prefix, prefs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for prefix, expected := range saved {
+		if prefs, err := ReadPreferences(path, prefix); err != nil {
+			t.Errorf("%q: %v", prefix, err)
+		} else if !reflect.DeepEqual(prefs, expected) {
+			t.Errorf("%q: expected %+v, found %+v", prefix, expected, prefs)
+		}
+	}
+	if prefs, err := ReadPreferences(path, "other"); err != nil || !reflect.DeepEqual(prefs, &Preferences{}) {
+		t.Errorf("expected empty preferences for another prefix: %+v, %v", prefs, err)
+	}
+}
@@ -0,0 +1,290 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+var _ TenantRepository = (*GormTenantRepository)(nil)
+
+// GormTenantRepository is a TenantRepository using the given table of a gorm
+// database, context history is only recorded once DetectHistory or
+// MigrateHistory has found or created its table
+type GormTenantRepository struct {
+	db         *gorm.DB
+	table      string
+	hasHistory bool
+	// lockRows is set within a Transaction when the database supports SELECT
+	// FOR UPDATE, the tenants read by Get are then locked until it ends
+	lockRows bool
+}
+
+func NewGormTenantRepository(db *gorm.DB, table string) (repo *GormTenantRepository) {
+	if table == "" {
+		table = store.DefaultTableName
+	}
+	repo = &GormTenantRepository{db: db, table: table}
+	return
+}
+
+// withDB returns a copy of the repository using the given database session,
+// such as a transaction
+func (r *GormTenantRepository) withDB(db *gorm.DB) (repo *GormTenantRepository) {
+	repo = &GormTenantRepository{db: db, table: r.table, hasHistory: r.hasHistory}
+	return
+}
+
+func (r *GormTenantRepository) tx() (tx *gorm.DB) {
+	// new session so that conditions never accumulate between statements
+	tx = r.db.Table(r.table).Session(&gorm.Session{})
+	return
+}
+
+func (r *GormTenantRepository) filter(tx *gorm.DB, filter TenantFilter) *gorm.DB {
+	if len(filter.ClientKeys) > 0 {
+		tx = tx.Where("client_key IN ?", filter.ClientKeys)
+	}
+	if filter.BaseURL != "" {
+		// "!" rather than a backslash escapes as MySQL string literals take
+		// backslash escapes of their own
+		tx = tx.Where("LOWER(base_url) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(filter.BaseURL))+"%")
+	}
+	if filter.Installed != nil {
+		tx = tx.Where("addon_installed = ?", *filter.Installed)
+	}
+	return tx
+}
+
+// escapeLike escapes the LIKE wildcards within the text, for the ESCAPE '!'
+// clause of the filter
+func escapeLike(text string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(text)
+}
+
+// licenseExpr returns the SQL expression of the context license for the
+// database, empty when absent or when the context is malformed so that the
+// order is the same as with the memory repository; ok is false for databases
+// without JSON functions known to the console
+func (r *GormTenantRepository) licenseExpr() (sql string, ok bool) {
+	switch r.db.Dialector.Name() {
+	case "sqlite":
+		// only sqlite stores the context as text which may be malformed
+		sql, ok = "COALESCE(CASE WHEN json_valid(context) THEN json_extract(context, '$.license') END, '')", true
+	case "postgres":
+		sql, ok = "COALESCE(context->>'license', '')", true
+	case "mysql":
+		sql, ok = "COALESCE(JSON_UNQUOTE(JSON_EXTRACT(context, '$.license')), '')", true
+	}
+	return
+}
+
+// orderBy returns the gorm ORDER BY clause for the sorts, client_key is the
+// final tie-breaker for stable pages
+func (r *GormTenantRepository) orderBy(sorts []TenantSort) (order clause.OrderBy) {
+	var exprs []clause.Expression
+	for _, s := range sorts {
+		direction := " ASC"
+		if s.Desc {
+			direction = " DESC"
+		}
+		if s.Field == SortByLicense {
+			license, _ := r.licenseExpr()
+			exprs = append(exprs, clause.Expr{SQL: license + direction, WithoutParentheses: true})
+			continue
+		}
+		value := clause.Column{Name: string(s.Field)}
+		exprs = append(exprs, clause.Expr{SQL: "?" + direction, Vars: []interface{}{value}, WithoutParentheses: true})
+	}
+	exprs = append(exprs, clause.Expr{SQL: "? ASC", Vars: []interface{}{clause.Column{Name: "client_key"}}, WithoutParentheses: true})
+	order = clause.OrderBy{Expression: clause.CommaExpression{Exprs: exprs}}
+	return
+}
+
+func (r *GormTenantRepository) List(query TenantQuery) (tenants []*store.Tenant, err error) {
+	if err = query.validate(); err != nil {
+		return
+	}
+	tx := r.filter(r.tx(), query.Filter)
+	if _, ok := r.licenseExpr(); query.sortsContext() && !ok {
+		// the license cannot be extracted by this database, these are sorted
+		// in the same way as the memory repository
+		if err = tx.Find(&tenants).Error; err != nil {
+			err = fmt.Errorf("error listing tenants: %v", err)
+			return
+		}
+		sortTenants(tenants, query.Sort)
+		tenants = pageTenants(tenants, query.Offset, query.Limit)
+		return
+	}
+	tx = tx.Clauses(r.orderBy(query.Sort))
+	if query.Offset > 0 {
+		tx = tx.Offset(query.Offset)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	if err = tx.Find(&tenants).Error; err != nil {
+		err = fmt.Errorf("error listing tenants: %v", err)
+	}
+	return
+}
+
+func (r *GormTenantRepository) Count(filter TenantFilter) (count int64, err error) {
+	if err = r.filter(r.tx(), filter).Count(&count).Error; err != nil {
+		err = fmt.Errorf("error counting tenants: %v", err)
+	}
+	return
+}
+
+func (r *GormTenantRepository) Get(clientKey string) (tenant *store.Tenant, err error) {
+	tenant = &store.Tenant{}
+	tx := r.tx()
+	if r.lockRows {
+		tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if err = tx.Where("client_key = ?", clientKey).First(tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = ErrTenantNotFound
+		}
+		tenant = nil
+	}
+	return
+}
+
+// DetectHistory enables the context history when its table is present, the
+// table is only ever created by MigrateHistory
+func (r *GormTenantRepository) DetectHistory() (enabled bool) {
+	r.hasHistory = r.db.Migrator().HasTable(r.historyTable())
+	return r.hasHistory
+}
+
+// MigrateHistory creates or updates the table of context snapshots and enables
+// the context history
+func (r *GormTenantRepository) MigrateHistory() (err error) {
+	if err = r.history().AutoMigrate(&ContextSnapshot{}); err != nil {
+		return fmt.Errorf("error migrating context history table: %v", err)
+	}
+	// index names are shared by all the tables of some databases
+	index := "idx_" + r.historyTable() + "_version"
+	if !r.history().Migrator().HasIndex(&ContextSnapshot{}, index) {
+		sql := "CREATE UNIQUE INDEX ? ON ? (client_key, version)"
+		if err = r.db.Exec(sql, clause.Table{Name: index}, clause.Table{Name: r.historyTable()}).Error; err != nil {
+			return fmt.Errorf("error indexing context history table: %v", err)
+		}
+	}
+	r.hasHistory = true
+	return
+}
+
+func (r *GormTenantRepository) historyTable() string {
+	return r.table + HistoryTableSuffix
+}
+
+func (r *GormTenantRepository) history() (tx *gorm.DB) {
+	tx = r.db.Table(r.historyTable()).Session(&gorm.Session{})
+	return
+}
+
+func (r *GormTenantRepository) UpdateContext(tenant *store.Tenant, ctx TenantContext) (err error) {
+	var before TenantContext
+	if before, err = ParseTenantContext(tenant); err != nil {
+		return
+	}
+	updated := copyTenant(tenant)
+	if err = ctx.Apply(updated); err != nil {
+		return
+	}
+	now := time.Now()
+	err = r.db.Transaction(func(tx *gorm.DB) (err error) {
+		repo := r.withDB(tx)
+		// only the context and its time are written, the other columns are
+		// maintained by features-gonnectian and may have changed since the
+		// tenant was read
+		result := repo.tx().Where("client_key = ?", tenant.ClientKey).Updates(map[string]interface{}{
+			"context":    updated.Context,
+			"updated_at": now,
+		})
+		if err = result.Error; err != nil {
+			return fmt.Errorf("error saving tenant database change: %v - %v", tenant.BaseURL, err)
+		} else if result.RowsAffected == 0 {
+			// some databases do not count rows updated with the same value
+			var count int64
+			if err = repo.tx().Where("client_key = ?", tenant.ClientKey).Count(&count).Error; err != nil {
+				return fmt.Errorf("error saving tenant database change: %v - %v", tenant.BaseURL, err)
+			} else if count == 0 {
+				return fmt.Errorf("%w: %v", ErrTenantNotFound, tenant.BaseURL)
+			}
+		}
+		if !repo.hasHistory {
+			return
+		}
+		var latest int
+		if err = repo.history().Where("client_key = ?", tenant.ClientKey).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("error reading context history: %v - %v", tenant.BaseURL, err)
+		}
+		var snapshots []*ContextSnapshot
+		if snapshots, err = makeSnapshots(tenant, before, ctx, latest); err != nil || len(snapshots) == 0 {
+			return
+		} else if err = repo.history().Create(snapshots).Error; err != nil {
+			return fmt.Errorf("error saving context history: %v - %v", tenant.BaseURL, err)
+		}
+		return
+	})
+	if err == nil {
+		tenant.Context = updated.Context
+		tenant.UpdatedAt = now
+	}
+	return
+}
+
+func (r *GormTenantRepository) History(clientKey string) (snapshots []*ContextSnapshot, err error) {
+	if !r.hasHistory {
+		return nil, ErrHistoryDisabled
+	}
+	if err = r.history().Where("client_key = ?", clientKey).Order("version").Find(&snapshots).Error; err != nil {
+		err = fmt.Errorf("error listing context history: %v - %v", clientKey, err)
+	}
+	return
+}
+
+func (r *GormTenantRepository) Delete(clientKey string) (err error) {
+	if err = r.tx().Where("client_key = ?", clientKey).Delete(&store.Tenant{}).Error; err != nil {
+		err = fmt.Errorf("error deleting tenant: %v - %v", clientKey, err)
+	}
+	return
+}
+
+func (r *GormTenantRepository) Transaction(fn func(repo TenantRepository) (err error)) (err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) (err error) {
+		repo := r.withDB(tx)
+		switch tx.Dialector.Name() {
+		case "postgres", "mysql":
+			repo.lockRows = true
+		}
+		return fn(repo)
+	})
+	return
+}
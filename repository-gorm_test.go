@@ -0,0 +1,126 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+// gormTenants returns a repository of the tenants within an in-memory sqlite
+// database of the test
+func gormTenants(t *testing.T, tenants ...*store.Tenant) (repo *GormTenantRepository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%v?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	} else if err = db.Table(store.DefaultTableName).AutoMigrate(&store.Tenant{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range tenants {
+		if err = db.Table(store.DefaultTableName).Create(tenant).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo = NewGormTenantRepository(db, store.DefaultTableName)
+	return
+}
+
+func TestGormTenantRepositoryLicenseSort(t *testing.T) {
+	repo := gormTenants(t,
+		&store.Tenant{ClientKey: "key-a", SharedSecret: "a", BaseURL: "https://alpha.atlassian.net", Context: []byte(`{"license":"none"}`)},
+		&store.Tenant{ClientKey: "key-b", SharedSecret: "b", BaseURL: "https://beta.atlassian.net", Context: []byte(`{"license":"active"}`)},
+		&store.Tenant{ClientKey: "key-c", SharedSecret: "c", BaseURL: "https://gamma.atlassian.net", Context: []byte(`{"license":`)},
+		&store.Tenant{ClientKey: "key-d", SharedSecret: "d", BaseURL: "https://delta.atlassian.net", Context: []byte(`{"license":"active"}`)},
+	)
+
+	for _, test := range []struct {
+		name     string
+		query    TenantQuery
+		expected string
+	}{
+		{name: "license", query: TenantQuery{Sort: []TenantSort{{Field: SortByLicense}}}, expected: "[key-c key-b key-d key-a]"},
+		{name: "license desc", query: TenantQuery{Sort: []TenantSort{{Field: SortByLicense, Desc: true}}}, expected: "[key-a key-b key-d key-c]"},
+		{name: "license then url", query: TenantQuery{Sort: []TenantSort{{Field: SortByLicense}, {Field: SortByBaseURL, Desc: true}}}, expected: "[key-c key-d key-b key-a]"},
+		{name: "license page", query: TenantQuery{Sort: []TenantSort{{Field: SortByLicense}}, Offset: 1, Limit: 2}, expected: "[key-b key-d]"},
+		{name: "license filter", query: TenantQuery{Filter: TenantFilter{BaseURL: "ta."}, Sort: []TenantSort{{Field: SortByLicense}}}, expected: "[key-b key-d]"},
+		{name: "column", query: TenantQuery{Sort: []TenantSort{{Field: SortByBaseURL}}, Limit: 2}, expected: "[key-a key-b]"},
+	} {
+		if tenants, err := repo.List(test.query); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if keys := fmt.Sprint(clientKeys(tenants)); keys != test.expected {
+			t.Errorf("%v: expected %v, found %v", test.name, test.expected, keys)
+		}
+	}
+}
+
+func TestGormTenantRepositoryFilter(t *testing.T) {
+	repo := gormTenants(t,
+		&store.Tenant{ClientKey: "key-d", SharedSecret: "d", BaseURL: "https://a_b.example.com", ProductType: "jira"},
+		&store.Tenant{ClientKey: "key-c", SharedSecret: "c", BaseURL: "https://axb.example.com", ProductType: "jira"},
+		&store.Tenant{ClientKey: "key-b", SharedSecret: "b", BaseURL: "https://100%.example.com", ProductType: "jira"},
+		&store.Tenant{ClientKey: "key-a", SharedSecret: "a", BaseURL: "https://Mixed!Case.example.com", ProductType: "jira"},
+	)
+	for _, test := range []struct {
+		name     string
+		query    TenantQuery
+		expected string
+	}{
+		{name: "underscore", query: TenantQuery{Filter: TenantFilter{BaseURL: "a_b"}}, expected: "[key-d]"},
+		{name: "percent", query: TenantQuery{Filter: TenantFilter{BaseURL: "0%."}}, expected: "[key-b]"},
+		{name: "escape", query: TenantQuery{Filter: TenantFilter{BaseURL: "d!c"}}, expected: "[key-a]"},
+		{name: "case", query: TenantQuery{Filter: TenantFilter{BaseURL: "MIXED"}}, expected: "[key-a]"},
+		{name: "tie-break", query: TenantQuery{Sort: []TenantSort{{Field: SortByProductType}}}, expected: "[key-a key-b key-c key-d]"},
+		{name: "unsorted", expected: "[key-a key-b key-c key-d]"},
+	} {
+		if tenants, err := repo.List(test.query); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if keys := fmt.Sprint(clientKeys(tenants)); keys != test.expected {
+			t.Errorf("%v: expected %v, found %v", test.name, test.expected, keys)
+		}
+	}
+}
+
+func TestGormTenantRepositoryUpdateContext(t *testing.T) {
+	created := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := gormTenants(t, &store.Tenant{ClientKey: "key-a", SharedSecret: "a", BaseURL: "https://alpha.atlassian.net", CreatedAt: created, UpdatedAt: created, Context: []byte(`{"debug":"false"}`)})
+	tenant, err := repo.Get("key-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ := ParseTenantContext(tenant)
+	ctx.EnableDebug(0)
+	if err = repo.UpdateContext(tenant, ctx); err != nil {
+		t.Fatal(err)
+	} else if !tenant.UpdatedAt.After(created) {
+		t.Errorf("expected the tenant updated after %v, found %v", created, tenant.UpdatedAt)
+	}
+	if stored, err := repo.Get("key-a"); err != nil {
+		t.Fatal(err)
+	} else if !stored.UpdatedAt.After(created) {
+		t.Errorf("expected the stored tenant updated after %v, found %v", created, stored.UpdatedAt)
+	} else if ctx, _ = ParseTenantContext(stored); !ctx.Debug() {
+		t.Errorf("expected the stored context updated, found %v", string(stored.Context))
+	}
+}
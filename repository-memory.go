@@ -0,0 +1,243 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+var _ TenantRepository = (*MemoryTenantRepository)(nil)
+
+// MemoryTenantRepository is a TenantRepository kept entirely in memory, for
+// tests and demonstrations; tenants are copied in and out so that callers
+// never share records with the repository
+type MemoryTenantRepository struct {
+	tenants map[string]*store.Tenant
+	history map[string][]*ContextSnapshot
+
+	sync.RWMutex
+}
+
+func NewMemoryTenantRepository(tenants ...*store.Tenant) (repo *MemoryTenantRepository) {
+	repo = &MemoryTenantRepository{
+		tenants: make(map[string]*store.Tenant),
+		history: make(map[string][]*ContextSnapshot),
+	}
+	for _, tenant := range tenants {
+		repo.tenants[tenant.ClientKey] = copyTenant(tenant)
+	}
+	return
+}
+
+func copyTenant(tenant *store.Tenant) (c *store.Tenant) {
+	c = new(store.Tenant)
+	*c = *tenant
+	if tenant.Context != nil {
+		c.Context = append([]byte{}, tenant.Context...)
+	}
+	return
+}
+
+func (r *MemoryTenantRepository) match(tenant *store.Tenant, filter TenantFilter) bool {
+	if len(filter.ClientKeys) > 0 {
+		var found bool
+		for _, key := range filter.ClientKeys {
+			if found = key == tenant.ClientKey; found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.BaseURL != "" && !strings.Contains(strings.ToLower(tenant.BaseURL), strings.ToLower(filter.BaseURL)) {
+		return false
+	}
+	if filter.Installed != nil && *filter.Installed != tenant.AddonInstalled {
+		return false
+	}
+	return true
+}
+
+func (r *MemoryTenantRepository) List(query TenantQuery) (tenants []*store.Tenant, err error) {
+	if err = query.validate(); err != nil {
+		return
+	}
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, tenant := range r.tenants {
+		if r.match(tenant, query.Filter) {
+			tenants = append(tenants, copyTenant(tenant))
+		}
+	}
+
+	sortTenants(tenants, query.Sort)
+	tenants = pageTenants(tenants, query.Offset, query.Limit)
+	return
+}
+
+// sortTenants orders the tenants by the sorts, ClientKey is the final
+// tie-breaker for stable pages
+func sortTenants(tenants []*store.Tenant, sorts []TenantSort) {
+	sort.SliceStable(tenants, func(i, j int) bool {
+		for _, s := range sorts {
+			if c := compareTenants(tenants[i], tenants[j], s.Field); c != 0 {
+				return (c < 0) != s.Desc
+			}
+		}
+		return tenants[i].ClientKey < tenants[j].ClientKey
+	})
+}
+
+// pageTenants returns the page of sorted tenants, a zero limit returns all the
+// tenants after the offset
+func pageTenants(tenants []*store.Tenant, offset, limit int) (page []*store.Tenant) {
+	if offset >= len(tenants) {
+		return
+	}
+	page = tenants[offset:]
+	if limit > 0 && limit < len(page) {
+		page = page[:limit]
+	}
+	return
+}
+
+func (r *MemoryTenantRepository) Count(filter TenantFilter) (count int64, err error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, tenant := range r.tenants {
+		if r.match(tenant, filter) {
+			count += 1
+		}
+	}
+	return
+}
+
+func (r *MemoryTenantRepository) Get(clientKey string) (tenant *store.Tenant, err error) {
+	r.RLock()
+	defer r.RUnlock()
+	if found, ok := r.tenants[clientKey]; ok {
+		tenant = copyTenant(found)
+		return
+	}
+	err = ErrTenantNotFound
+	return
+}
+
+func (r *MemoryTenantRepository) UpdateContext(tenant *store.Tenant, ctx TenantContext) (err error) {
+	var before TenantContext
+	if before, err = ParseTenantContext(tenant); err != nil {
+		return
+	}
+	updated := copyTenant(tenant)
+	if err = ctx.Apply(updated); err != nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.tenants[tenant.ClientKey]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrTenantNotFound, tenant.BaseURL)
+	}
+	history := r.history[tenant.ClientKey]
+	var snapshots []*ContextSnapshot
+	if snapshots, err = makeSnapshots(tenant, before, ctx, len(history)); err != nil {
+		return
+	}
+	r.history[tenant.ClientKey] = append(history, snapshots...)
+	// only the context and its time are written, as with the
+	// GormTenantRepository
+	now := time.Now()
+	stored.Context = append([]byte{}, updated.Context...)
+	stored.UpdatedAt = now
+	tenant.Context = updated.Context
+	tenant.UpdatedAt = now
+	return
+}
+
+func (r *MemoryTenantRepository) History(clientKey string) (snapshots []*ContextSnapshot, err error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, snapshot := range r.history[clientKey] {
+		c := *snapshot
+		snapshots = append(snapshots, &c)
+	}
+	return
+}
+
+func (r *MemoryTenantRepository) Delete(clientKey string) (err error) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.tenants, clientKey)
+	return
+}
+
+func (r *MemoryTenantRepository) Transaction(fn func(repo TenantRepository) (err error)) (err error) {
+	r.Lock()
+	defer r.Unlock()
+	tx := &MemoryTenantRepository{
+		tenants: make(map[string]*store.Tenant, len(r.tenants)),
+		history: make(map[string][]*ContextSnapshot, len(r.history)),
+	}
+	for key, tenant := range r.tenants {
+		tx.tenants[key] = copyTenant(tenant)
+	}
+	for key, snapshots := range r.history {
+		// snapshots are never modified, only appended
+		tx.history[key] = snapshots[:len(snapshots):len(snapshots)]
+	}
+	if err = fn(tx); err == nil {
+		r.tenants = tx.tenants
+		r.history = tx.history
+	}
+	return
+}
+
+func compareTenants(a, b *store.Tenant, field TenantSortField) (c int) {
+	switch field {
+	case SortByClientKey:
+		c = strings.Compare(a.ClientKey, b.ClientKey)
+	case SortByBaseURL:
+		c = strings.Compare(a.BaseURL, b.BaseURL)
+	case SortByProductType:
+		c = strings.Compare(a.ProductType, b.ProductType)
+	case SortByInstalled:
+		switch {
+		case a.AddonInstalled == b.AddonInstalled:
+		case a.AddonInstalled:
+			c = 1
+		default:
+			c = -1
+		}
+	case SortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByLicense:
+		ac, _ := ParseTenantContext(a)
+		bc, _ := ParseTenantContext(b)
+		c = strings.Compare(ac.License(), bc.License())
+	}
+	return
+}
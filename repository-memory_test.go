@@ -0,0 +1,166 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+func memoryTenants() []*store.Tenant {
+	return []*store.Tenant{
+		{ClientKey: "key-b", BaseURL: "https://beta.atlassian.net", ProductType: "jira", AddonInstalled: true, Context: []byte(`{"debug":"false","license":"none"}`)},
+		{ClientKey: "key-a", BaseURL: "https://alpha.atlassian.net", ProductType: "confluence", AddonInstalled: true, Context: []byte(`{"debug":"false","license":"active"}`)},
+		{ClientKey: "key-c", BaseURL: "https://gamma.example.com", ProductType: "jira", Context: []byte(`{"debug":"true"}`)},
+	}
+}
+
+func clientKeys(tenants []*store.Tenant) (keys []string) {
+	for _, tenant := range tenants {
+		keys = append(keys, tenant.ClientKey)
+	}
+	return
+}
+
+func TestMemoryTenantRepositoryList(t *testing.T) {
+	repo := NewMemoryTenantRepository(memoryTenants()...)
+	installed := true
+	for _, test := range []struct {
+		name     string
+		query    TenantQuery
+		expected string
+		invalid  bool
+	}{
+		{name: "default", expected: "[key-a key-b key-c]"},
+		{name: "base url", query: TenantQuery{Sort: []TenantSort{{Field: SortByBaseURL}}}, expected: "[key-a key-b key-c]"},
+		{name: "product desc", query: TenantQuery{Sort: []TenantSort{{Field: SortByProductType, Desc: true}}}, expected: "[key-b key-c key-a]"},
+		{name: "license", query: TenantQuery{Sort: []TenantSort{{Field: SortByLicense}}}, expected: "[key-c key-a key-b]"},
+		{name: "page", query: TenantQuery{Offset: 1, Limit: 1}, expected: "[key-b]"},
+		{name: "past the end", query: TenantQuery{Offset: 5}, expected: "[]"},
+		{name: "installed", query: TenantQuery{Filter: TenantFilter{Installed: &installed}}, expected: "[key-a key-b]"},
+		{name: "base url filter", query: TenantQuery{Filter: TenantFilter{BaseURL: "atlassian"}}, expected: "[key-a key-b]"},
+		{name: "base url filter case", query: TenantQuery{Filter: TenantFilter{BaseURL: "ATLASSIAN"}}, expected: "[key-a key-b]"},
+		{name: "client keys", query: TenantQuery{Filter: TenantFilter{ClientKeys: []string{"key-c", "key-x"}}}, expected: "[key-c]"},
+		{name: "invalid sort", query: TenantQuery{Sort: []TenantSort{{Field: "secret"}}}, invalid: true},
+		{name: "invalid page", query: TenantQuery{Offset: -1}, invalid: true},
+	} {
+		tenants, err := repo.List(test.query)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.name)
+			}
+		} else if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if keys := fmt.Sprint(clientKeys(tenants)); keys != test.expected {
+			t.Errorf("%v: expected %v, found %v", test.name, test.expected, keys)
+		}
+	}
+}
+
+func TestMemoryTenantRepositoryCopies(t *testing.T) {
+	tenants := memoryTenants()
+	repo := NewMemoryTenantRepository(tenants...)
+
+	// changes to the seeded and returned tenants are not seen by the repository
+	tenants[1].BaseURL = "https://changed.atlassian.net"
+	tenants[1].Context[2] = 'X'
+	found, err := repo.Get("key-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.BaseURL = "https://changed.atlassian.net"
+	found.Context[2] = 'X'
+	if found, err = repo.Get("key-a"); err != nil {
+		t.Fatal(err)
+	} else if found.BaseURL != "https://alpha.atlassian.net" || string(found.Context) != `{"debug":"false","license":"active"}` {
+		t.Errorf("expected the stored tenant unchanged: %v %s", found.BaseURL, found.Context)
+	}
+
+	// only the context is written by UpdateContext
+	ctx, _ := ParseTenantContext(found)
+	ctx.EnableDebug(0)
+	found.BaseURL = "https://changed.atlassian.net"
+	if err = repo.UpdateContext(found, ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx[CtxKeyLicense] = "changed"
+	if found, err = repo.Get("key-a"); err != nil {
+		t.Fatal(err)
+	} else if stored, _ := ParseTenantContext(found); !stored.Debug() || stored.License() != "active" || found.BaseURL != "https://alpha.atlassian.net" {
+		t.Errorf("expected only the debug change stored: %v %s", found.BaseURL, found.Context)
+	}
+
+	if _, err = repo.Get("key-x"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("expected ErrTenantNotFound, found %v", err)
+	}
+}
+
+func TestMemoryTenantRepositoryTransaction(t *testing.T) {
+	repo := NewMemoryTenantRepository(memoryTenants()...)
+	update := func(tx TenantRepository, clientKey string) (err error) {
+		var tenant *store.Tenant
+		if tenant, err = tx.Get(clientKey); err != nil {
+			return
+		}
+		ctx, _ := ParseTenantContext(tenant)
+		ctx.EnableDebug(0)
+		return tx.UpdateContext(tenant, ctx)
+	}
+
+	// a failed transaction keeps none of its changes
+	failure := errors.New("failure")
+	err := repo.Transaction(func(tx TenantRepository) (err error) {
+		if err = update(tx, "key-a"); err != nil {
+			return
+		} else if err = tx.Delete("key-b"); err != nil {
+			return
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the transaction error, found %v", err)
+	} else if count, _ := repo.Count(TenantFilter{}); count != 3 {
+		t.Errorf("expected the deleted tenant restored, found %d tenants", count)
+	} else if tenant, _ := repo.Get("key-a"); tenant == nil {
+		t.Errorf("expected key-a present")
+	} else if ctx, _ := ParseTenantContext(tenant); ctx.Debug() {
+		t.Errorf("expected the context change rolled back: %s", tenant.Context)
+	} else if history, _ := repo.History("key-a"); len(history) != 0 {
+		t.Errorf("expected the history rolled back, found %d snapshots", len(history))
+	}
+
+	// a successful transaction keeps all of its changes
+	if err = repo.Transaction(func(tx TenantRepository) (err error) {
+		if err = update(tx, "key-a"); err != nil {
+			return
+		}
+		return tx.Delete("key-b")
+	}); err != nil {
+		t.Fatal(err)
+	} else if count, _ := repo.Count(TenantFilter{}); count != 2 {
+		t.Errorf("expected the tenant deleted, found %d tenants", count)
+	} else if tenant, _ := repo.Get("key-a"); tenant == nil {
+		t.Errorf("expected key-a present")
+	} else if ctx, _ := ParseTenantContext(tenant); !ctx.Debug() {
+		t.Errorf("expected the context change kept: %s", tenant.Context)
+	} else if history, _ := repo.History("key-a"); len(history) != 2 {
+		t.Errorf("expected the initial and changed snapshots, found %d", len(history))
+	}
+}
@@ -0,0 +1,155 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+var ErrTenantNotFound = errors.New("tenant not found")
+
+var ErrHistoryDisabled = errors.New("context history is disabled until its table is created with the migrate command")
+
+var ErrVersionNotFound = errors.New("context version not found")
+
+// TenantRepository is the storage used by the console for reading and
+// updating store.Tenant records
+type TenantRepository interface {
+	// List returns the tenants matching the query
+	List(query TenantQuery) (tenants []*store.Tenant, err error)
+	// Count returns the number of tenants matching the filter
+	Count(filter TenantFilter) (count int64, err error)
+	// Get returns the tenant with the given ClientKey or ErrTenantNotFound
+	Get(clientKey string) (tenant *store.Tenant, err error)
+	// UpdateContext applies the context to the tenant and saves it, recording
+	// a ContextSnapshot when the context changed
+	UpdateContext(tenant *store.Tenant, ctx TenantContext) (err error)
+	// History returns the context snapshots of the tenant, oldest first, or
+	// ErrHistoryDisabled when the history is not recorded
+	History(clientKey string) (snapshots []*ContextSnapshot, err error)
+	// Delete removes the tenant with the given ClientKey
+	Delete(clientKey string) (err error)
+	// Transaction calls fn with a repository that keeps either all of the
+	// changes made or none of them, depending on fn returning nil
+	Transaction(fn func(repo TenantRepository) (err error)) (err error)
+}
+
+// TenantQuery selects a sorted page of tenants, a zero Limit returns all
+// tenants after the Offset
+type TenantQuery struct {
+	Filter TenantFilter
+	Sort   []TenantSort
+	Offset int
+	Limit  int
+}
+
+// TenantFilter narrows a query to tenants matching all the non-zero fields
+type TenantFilter struct {
+	// ClientKeys matches any of the given keys
+	ClientKeys []string
+	// BaseURL matches tenants containing the given text, ignoring case
+	BaseURL string
+	// Installed matches the AddonInstalled state
+	Installed *bool
+}
+
+type TenantSortField string
+
+const (
+	SortByClientKey   TenantSortField = "client_key"
+	SortByBaseURL     TenantSortField = "base_url"
+	SortByProductType TenantSortField = "product_type"
+	SortByInstalled   TenantSortField = "addon_installed"
+	SortByCreatedAt   TenantSortField = "created_at"
+	SortByUpdatedAt   TenantSortField = "updated_at"
+	// SortByLicense orders by the license recorded within the context
+	SortByLicense TenantSortField = "license"
+)
+
+var TenantSortFields = []TenantSortField{
+	SortByClientKey,
+	SortByBaseURL,
+	SortByProductType,
+	SortByInstalled,
+	SortByCreatedAt,
+	SortByUpdatedAt,
+	SortByLicense,
+}
+
+var tenantSortLabels = map[TenantSortField]string{
+	SortByClientKey:   "client key",
+	SortByBaseURL:     "base url",
+	SortByProductType: "product",
+	SortByInstalled:   "installed",
+	SortByCreatedAt:   "created",
+	SortByUpdatedAt:   "updated",
+	SortByLicense:     "license",
+}
+
+func (f TenantSortField) Valid() bool {
+	for _, field := range TenantSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (f TenantSortField) Label() string {
+	if label, ok := tenantSortLabels[f]; ok {
+		return label
+	}
+	return string(f)
+}
+
+type TenantSort struct {
+	Field TenantSortField
+	Desc  bool
+}
+
+func (s TenantSort) String() string {
+	if s.Desc {
+		return string(s.Field) + " desc"
+	}
+	return string(s.Field)
+}
+
+// sortsContext reports if any of the sorts is by a field of the context
+// rather than a column of the table
+func (q TenantQuery) sortsContext() bool {
+	for _, s := range q.Sort {
+		if s.Field == SortByLicense {
+			return true
+		}
+	}
+	return false
+}
+
+func (q TenantQuery) validate() (err error) {
+	for _, s := range q.Sort {
+		if !s.Field.Valid() {
+			return fmt.Errorf("invalid tenant sort field: %q", s.Field)
+		}
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		err = fmt.Errorf("invalid tenant page: offset=%d, limit=%d", q.Offset, q.Limit)
+	}
+	return
+}
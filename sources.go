@@ -0,0 +1,139 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+// TenantSource is a labelled tenant table within one of the enjin databases
+type TenantSource struct {
+	Label string
+	DbTag string
+	Table string
+
+	repo TenantRepository
+}
+
+// SourceChangedPanel is implemented by panels which need to reset any state
+// when a different TenantSource is selected
+type SourceChangedPanel interface {
+	SourceChanged()
+}
+
+// AddSource registers an additional named tenant table, the table given with
+// SetGormDB and SetTableName is always the first source
+func (f *CConsole) AddSource(label, dbTag, table string) MakeConsole {
+	f.sources = append(f.sources, &TenantSource{Label: label, DbTag: dbTag, Table: table})
+	return f
+}
+
+// validateSources checks that every source has a label, db tag and table name
+// and that the labels are unique
+func validateSources(sources []*TenantSource) (err error) {
+	labels := make(map[string]bool)
+	for _, src := range sources {
+		if src.Label == "" || src.DbTag == "" || src.Table == "" {
+			return fmt.Errorf("source requires a label, db tag and table name: %+v", *src)
+		} else if labels[src.Label] {
+			return fmt.Errorf("source labels must be unique: %q", src.Label)
+		}
+		labels[src.Label] = true
+	}
+	return
+}
+
+// Sources returns the list of configured tenant sources
+func (f *CConsole) Sources() (sources []*TenantSource) {
+	sources = append(sources, f.sources...)
+	return
+}
+
+// Source returns the currently selected tenant source
+func (f *CConsole) Source() (source *TenantSource) {
+	f.sourceLock.RLock()
+	defer f.sourceLock.RUnlock()
+	if f.source >= 0 && f.source < len(f.sources) {
+		source = f.sources[f.source]
+	}
+	return
+}
+
+// SelectSource switches the console to the tenant source at the given index
+func (f *CConsole) SelectSource(idx int) (err error) {
+	if idx < 0 || idx >= len(f.sources) {
+		return fmt.Errorf("tenant source index out of range: %d", idx)
+	} else if f.sources[idx].repo == nil {
+		return fmt.Errorf("tenant source not prepared: %v", f.sources[idx].Label)
+	}
+	f.sourceLock.Lock()
+	defer f.sourceLock.Unlock()
+	f.source = idx
+	f.repo = f.sources[idx].repo
+	return
+}
+
+// currentRepo returns the repository of the currently selected tenant source
+func (f *CConsole) currentRepo() (repo TenantRepository) {
+	f.sourceLock.RLock()
+	defer f.sourceLock.RUnlock()
+	return f.repo
+}
+
+// FindSource returns the index of the tenant source with the given label
+func (f *CConsole) FindSource(label string) (idx int, ok bool) {
+	for idx = range f.sources {
+		if ok = f.sources[idx].Label == label; ok {
+			return
+		}
+	}
+	idx = -1
+	return
+}
+
+// prepareSources constructs the repository for each source, using lookup to
+// find the enjin database by tag, or the synthetic tenants in demo mode
+func (f *CConsole) prepareSources(lookup func(tag string) (db interface{}, err error)) (err error) {
+	for idx, src := range f.sources {
+		if f.demo {
+			src.repo = NewMemoryTenantRepository(DemoTenants(DemoTenantCount, DemoSeed+int64(idx))...)
+			continue
+		}
+		var v interface{}
+		if v, err = lookup(src.DbTag); err != nil {
+			return fmt.Errorf("error getting enjin db %q: %v", src.DbTag, err)
+		}
+		db, ok := v.(*gorm.DB)
+		if !ok {
+			return fmt.Errorf("error preparing enjin db; expected *gorm.DB, received: %T", v)
+		}
+		if idx == 0 {
+			f.db = db
+		}
+		repo := NewGormTenantRepository(db, src.Table)
+		if !repo.DetectHistory() {
+			log.WarnF("context history disabled for %v, run the %v migrate command to enable it", src.Label, f.Tag().Kebab())
+		}
+		src.repo = repo
+	}
+	err = f.SelectSource(0)
+	return
+}
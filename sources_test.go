@@ -0,0 +1,174 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+// sourcesConsole returns a console with a memory repository source for each
+// label, the tenants of each are those of sweepTenants with the label as a
+// suffix of their ClientKeys and BaseURLs
+func sourcesConsole(t *testing.T, labels ...string) (f *CConsole) {
+	t.Helper()
+	f = &CConsole{}
+	for _, label := range labels {
+		var tenants []*store.Tenant
+		for _, tenant := range sweepTenants() {
+			tenant.AddonInstalled = tenant.ClientKey != "key-d"
+			tenant.ClientKey += "-" + label
+			tenant.BaseURL += "/" + label
+			tenants = append(tenants, tenant)
+		}
+		f.sources = append(f.sources, &TenantSource{Label: label, DbTag: "db", Table: label, repo: NewMemoryTenantRepository(tenants...)})
+	}
+	if err := f.SelectSource(0); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestValidateSources(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		sources []*TenantSource
+		invalid bool
+	}{
+		{name: "none"},
+		{name: "unique", sources: []*TenantSource{{Label: "one", DbTag: "db", Table: "a"}, {Label: "two", DbTag: "db", Table: "b"}}},
+		{name: "duplicate", sources: []*TenantSource{{Label: "one", DbTag: "db", Table: "a"}, {Label: "one", DbTag: "other", Table: "b"}}, invalid: true},
+		{name: "no label", sources: []*TenantSource{{DbTag: "db", Table: "a"}}, invalid: true},
+		{name: "no table", sources: []*TenantSource{{Label: "one", DbTag: "db"}}, invalid: true},
+	} {
+		if err := validateSources(test.sources); test.invalid && err == nil {
+			t.Errorf("%v: expected an error", test.name)
+		} else if !test.invalid && err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		}
+	}
+}
+
+func TestSelectSource(t *testing.T) {
+	f := sourcesConsole(t, "one", "two")
+	if src := f.Source(); src == nil || src.Label != "one" || f.currentRepo() != src.repo {
+		t.Fatalf("expected the first source selected, found %v", src)
+	}
+
+	idx, ok := f.FindSource("two")
+	if !ok || idx != 1 {
+		t.Fatalf("expected the second source found, found %v %v", idx, ok)
+	} else if err := f.SelectSource(idx); err != nil {
+		t.Fatal(err)
+	} else if src := f.Source(); src.Label != "two" || f.currentRepo() != src.repo {
+		t.Fatalf("expected the second source selected, found %v", src.Label)
+	} else if tenant, err := f.currentRepo().Get("key-a-two"); err != nil || tenant.BaseURL != "https://alpha.atlassian.net/two" {
+		t.Fatalf("expected the tenants of the second source, found %v %v", tenant, err)
+	}
+
+	if _, ok = f.FindSource("three"); ok {
+		t.Errorf("expected no source found")
+	}
+	f.sources = append(f.sources, &TenantSource{Label: "three"})
+	for _, idx := range []int{-1, 3, 2} {
+		if err := f.SelectSource(idx); err == nil {
+			t.Errorf("%d: expected an error", idx)
+		}
+	}
+	if src := f.Source(); src.Label != "two" {
+		t.Errorf("expected the second source kept, found %v", src.Label)
+	}
+}
+
+func TestPrepareSourcesDemo(t *testing.T) {
+	f := &CConsole{demo: true, sources: []*TenantSource{{Label: "one"}, {Label: "two"}}}
+	if err := f.prepareSources(nil); err != nil {
+		t.Fatal(err)
+	}
+	var keys [][]string
+	for _, src := range f.sources {
+		tenants, err := src.repo.List(TenantQuery{})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(tenants) != DemoTenantCount {
+			t.Errorf("%v: expected %d tenants, found %d", src.Label, DemoTenantCount, len(tenants))
+		}
+		keys = append(keys, clientKeys(tenants))
+	}
+	if reflect.DeepEqual(keys[0], keys[1]) {
+		t.Errorf("expected different tenants for each source")
+	} else if f.currentRepo() != f.sources[0].repo {
+		t.Errorf("expected the first source selected")
+	}
+}
+
+func TestSweepExpiredSources(t *testing.T) {
+	f := sourcesConsole(t, "one", "two")
+	swept, err := f.SweepExpired(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for key := range swept {
+		found = append(found, key)
+	}
+	// key-c has a malformed expiry and key-d a malformed context
+	expected := []string{
+		"[one] https://alpha.atlassian.net/one",
+		"[one] https://beta.atlassian.net/one",
+		"[two] https://alpha.atlassian.net/two",
+		"[two] https://beta.atlassian.net/two",
+	}
+	if sort.Strings(found); !reflect.DeepEqual(found, expected) {
+		t.Fatalf("expected %v swept, found %v", expected, found)
+	}
+
+	if _, err = f.SweepExpired(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range f.sources {
+		tenant, err := src.repo.Get("key-b-" + src.Label)
+		if err != nil {
+			t.Fatal(err)
+		} else if ctx, _ := ParseTenantContext(tenant); ctx.Debug() {
+			t.Errorf("%v: expected debugging disabled, found %v", src.Label, ctx)
+		}
+	}
+}
+
+func TestCollectMetricsSources(t *testing.T) {
+	f := sourcesConsole(t, "one", "two")
+	metrics, err := f.CollectMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, m := range metrics {
+		found = append(found, fmt.Sprintf("%v: %d installed, %d not, %d debug, %d errors", m.Source, m.Installed, m.NotInstalled, m.Debug, m.ParseErrors))
+	}
+	expected := []string{
+		"one: 3 installed, 1 not, 3 debug, 1 errors",
+		"two: 3 installed, 1 not, 3 debug, 1 errors",
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, found %v", expected, found)
+	}
+}
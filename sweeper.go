@@ -0,0 +1,127 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+
+	"github.com/go-enjin/be/pkg/log"
+)
+
+var SweepInterval = time.Minute
+
+// SweepExpired disables all expired time-limited tenant settings within all
+// tenant sources, returning the changes made keyed by tenant BaseURL (prefixed
+// with the source label when there is more than one source); dryRun reports
+// the changes without saving them
+func (f *CConsole) SweepExpired(dryRun bool) (swept map[string][]string, err error) {
+	swept = make(map[string][]string)
+	now := time.Now()
+	for _, src := range f.sources {
+		prefix := ""
+		if len(f.sources) > 1 {
+			prefix = "[" + src.Label + "] "
+		}
+		if err = sweepRepository(src.repo, prefix, now, swept, dryRun); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// sweepRepository sweeps the tenants of the repository which have settings
+// expired as of now. Each such tenant is read again and saved within a
+// transaction, so that any other change made since the tenants were listed,
+// by the console, the admin API or features-gonnectian, is kept
+func sweepRepository(repo TenantRepository, prefix string, now time.Time, swept map[string][]string, dryRun bool) (err error) {
+	var tenants []*store.Tenant
+	if tenants, err = repo.List(TenantQuery{}); err != nil {
+		return
+	}
+	for _, listed := range tenants {
+		var ctx TenantContext
+		if ctx, err = ParseTenantContext(listed); err != nil {
+			log.ErrorF("%v - %v", listed.BaseURL, err)
+			err = nil
+			continue
+		}
+		changes := ctx.SweepExpired(now)
+		if len(changes) == 0 {
+			continue
+		} else if dryRun {
+			swept[prefix+listed.BaseURL] = changes
+			continue
+		}
+		err = repo.Transaction(func(tx TenantRepository) (err error) {
+			var tenant *store.Tenant
+			if tenant, err = tx.Get(listed.ClientKey); err != nil {
+				return
+			} else if ctx, err = ParseTenantContext(tenant); err != nil {
+				log.ErrorF("%v - %v", tenant.BaseURL, err)
+				changes, err = nil, nil
+				return
+			} else if changes = ctx.SweepExpired(now); len(changes) > 0 {
+				err = tx.UpdateContext(tenant, ctx)
+			}
+			return
+		})
+		if errors.Is(err, ErrTenantNotFound) {
+			// removed since the tenants were listed
+			err = nil
+			continue
+		} else if err != nil {
+			return
+		} else if len(changes) > 0 {
+			swept[prefix+listed.BaseURL] = changes
+			log.InfoF("swept expired tenant settings: %v%v - %v", prefix, listed.BaseURL, changes)
+		}
+	}
+	return
+}
+
+// startSweeper runs SweepExpired every SweepInterval in the background,
+// requesting a user interface refresh when anything was swept
+func (f *CConsole) startSweeper() {
+	stop := make(chan struct{})
+	f.sweeper = stop
+	go func() {
+		ticker := time.NewTicker(SweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if swept, err := f.SweepExpired(false); err != nil {
+					log.ErrorF("error sweeping expired tenant settings: %v", err)
+				} else if len(swept) > 0 {
+					f.requestRefresh()
+				}
+			}
+		}
+	}()
+}
+
+func (f *CConsole) stopSweeper() {
+	if f.sweeper != nil {
+		close(f.sweeper)
+		f.sweeper = nil
+	}
+}
@@ -0,0 +1,132 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+var sweepNow = time.Date(2023, 6, 10, 12, 0, 0, 0, time.UTC)
+
+func sweepTenants() []*store.Tenant {
+	return []*store.Tenant{
+		{ClientKey: "key-a", BaseURL: "https://alpha.atlassian.net", Context: []byte(`{"debug":"true","debug-expires":"2023-06-10T11:00:00Z"}`)},
+		{ClientKey: "key-b", BaseURL: "https://beta.atlassian.net", Context: []byte(`{"debug":"true","debug-expires":"2023-06-11T00:00:00Z"}`)},
+		{ClientKey: "key-c", BaseURL: "https://gamma.atlassian.net", Context: []byte(`{"debug":"true","debug-expires":"soon"}`)},
+		{ClientKey: "key-d", BaseURL: "https://delta.atlassian.net", Context: []byte(`{broken`)},
+	}
+}
+
+// contexts returns the stored context of each tenant of the repository
+func contexts(t *testing.T, repo TenantRepository) (found map[string]string) {
+	t.Helper()
+	tenants, err := repo.List(TenantQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found = make(map[string]string)
+	for _, tenant := range tenants {
+		found[tenant.ClientKey] = tenant.Context.String()
+	}
+	return
+}
+
+func TestSweepRepository(t *testing.T) {
+	repo := NewMemoryTenantRepository(sweepTenants()...)
+	before := contexts(t, repo)
+	expected := map[string][]string{"[src] https://alpha.atlassian.net": {"debug expired 2023-06-10 11:00 UTC"}}
+
+	swept := make(map[string][]string)
+	if err := sweepRepository(repo, "[src] ", sweepNow, swept, true); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(swept, expected) {
+		t.Errorf("dry run: expected %v, found %v", expected, swept)
+	} else if after := contexts(t, repo); !reflect.DeepEqual(after, before) {
+		t.Errorf("dry run: expected no changes, found %v", after)
+	}
+
+	swept = make(map[string][]string)
+	if err := sweepRepository(repo, "[src] ", sweepNow, swept, false); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(swept, expected) {
+		t.Errorf("expected %v, found %v", expected, swept)
+	}
+	before["key-a"] = `{"debug":"false"}`
+	if after := contexts(t, repo); !reflect.DeepEqual(after, before) {
+		t.Errorf("expected %v, found %v", before, after)
+	}
+}
+
+// changingRepository changes a tenant after listing them, as another operator
+// or features-gonnectian might while a sweep runs
+type changingRepository struct {
+	*MemoryTenantRepository
+	change func()
+}
+
+func (r *changingRepository) List(query TenantQuery) (tenants []*store.Tenant, err error) {
+	if tenants, err = r.MemoryTenantRepository.List(query); err == nil {
+		r.change()
+	}
+	return
+}
+
+func TestSweepRepositoryKeepsChanges(t *testing.T) {
+	memory := NewMemoryTenantRepository(sweepTenants()...)
+	repo := &changingRepository{MemoryTenantRepository: memory, change: func() {
+		tenant, _ := memory.Get("key-a")
+		ctx, _ := ParseTenantContext(tenant)
+		ctx[CtxKeyLicense] = "active"
+		if err := memory.UpdateContext(tenant, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}}
+	swept := make(map[string][]string)
+	if err := sweepRepository(repo, "", sweepNow, swept, false); err != nil {
+		t.Fatal(err)
+	} else if len(swept) != 1 {
+		t.Errorf("expected only alpha swept: %v", swept)
+	}
+	if found := contexts(t, memory)["key-a"]; found != `{"debug":"false","license":"active"}` {
+		t.Errorf("expected the license change kept: %v", found)
+	}
+}
+
+func TestWriteSwept(t *testing.T) {
+	swept := map[string][]string{
+		"https://beta.atlassian.net":  {"unlicensed grant expired 2023-06-01 00:00 UTC"},
+		"https://alpha.atlassian.net": {"debug expired 2023-06-10 11:00 UTC"},
+	}
+	for dryRun, expected := range map[bool]string{
+		true: "https://alpha.atlassian.net: debug expired 2023-06-10 11:00 UTC\n" +
+			"https://beta.atlassian.net: unlicensed grant expired 2023-06-01 00:00 UTC\n" +
+			"# 2 tenants have expired settings\n",
+		false: "https://alpha.atlassian.net: debug expired 2023-06-10 11:00 UTC\n" +
+			"https://beta.atlassian.net: unlicensed grant expired 2023-06-01 00:00 UTC\n" +
+			"# 2 tenants updated\n",
+	} {
+		var buf bytes.Buffer
+		if writeSwept(&buf, swept, dryRun); buf.String() != expected {
+			t.Errorf("dry run %v: expected %q, found %q", dryRun, expected, buf.String())
+		}
+	}
+}
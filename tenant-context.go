@@ -0,0 +1,330 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-enjin/github-com-craftamap-atlas-gonnect/store"
+)
+
+const (
+	CtxKeyDebug             = "debug"
+	CtxKeyDebugExpires      = "debug-expires"
+	CtxKeyAllowedUnlicensed = "allowed-unlicensed"
+	CtxKeyUnlicensedExpires = "allowed-unlicensed-expires"
+	CtxKeyUnlicensedReason  = "allowed-unlicensed-reason"
+	CtxKeyReject            = "reject"
+	CtxKeyFlagged           = "flagged"
+	CtxKeyFlaggedAt         = "flagged-at"
+	CtxKeyLicense           = "license"
+
+	// CtxKeyAppKey and CtxKeyAppBaseURL record the descriptor of the app
+	// installed, these are backfilled by the record-apps command, see
+	// CConsole.RecordTenantApps
+	CtxKeyAppKey     = "app-key"
+	CtxKeyAppBaseURL = "app-base-url"
+)
+
+// TenantContext is the parsed form of a store.Tenant Context JSON object
+type TenantContext map[string]interface{}
+
+func ParseTenantContext(tenant *store.Tenant) (ctx TenantContext, err error) {
+	ctx = make(TenantContext)
+	contextJson := tenant.Context.String()
+	if contextJson == "" {
+		contextJson = `{"debug":"false"}`
+	}
+	if err = json.Unmarshal([]byte(contextJson), &ctx); err != nil {
+		err = fmt.Errorf("error parsing tenant context: %v", err)
+	}
+	return
+}
+
+// Apply encodes the context and stores it on the given tenant, the caller is
+// responsible for saving the tenant
+func (c TenantContext) Apply(tenant *store.Tenant) (err error) {
+	var b []byte
+	if b, err = json.Marshal(c); err != nil {
+		err = fmt.Errorf("error encoding tenant context change: %v", err)
+		return
+	}
+	tenant.Context = b
+	return
+}
+
+// Copy returns a deep copy of the context
+func (c TenantContext) Copy() (other TenantContext) {
+	other = make(TenantContext)
+	if b, err := json.Marshal(c); err == nil {
+		_ = json.Unmarshal(b, &other)
+	}
+	return
+}
+
+func (c TenantContext) String(key string) (value string) {
+	if v, ok := c[key].(string); ok {
+		value = v
+	}
+	return
+}
+
+func (c TenantContext) Bool(key string) (value bool) {
+	if v, ok := c[key].(bool); ok {
+		value = v
+	}
+	return
+}
+
+func (c TenantContext) Time(key string) (value time.Time, ok bool) {
+	if v, present := c[key].(string); present && v != "" {
+		var err error
+		if value, err = time.Parse(time.RFC3339, v); err == nil {
+			ok = true
+		}
+	}
+	return
+}
+
+func (c TenantContext) License() (license string) {
+	if v, ok := c[CtxKeyLicense]; ok && v != nil {
+		license = fmt.Sprintf("%v", v)
+	}
+	return
+}
+
+// AppKey returns the descriptor key of the app installed, when recorded
+func (c TenantContext) AppKey() (key string) {
+	return c.String(CtxKeyAppKey)
+}
+
+// AppBaseURL returns the descriptor baseUrl of the app version installed,
+// when recorded
+func (c TenantContext) AppBaseURL() (url string) {
+	return c.String(CtxKeyAppBaseURL)
+}
+
+func (c TenantContext) Debug() (enabled bool) {
+	return c.String(CtxKeyDebug) == "true"
+}
+
+// DebugExpires returns the time debugging will be disabled automatically, ok
+// is false when debugging is not enabled or has no expiry
+func (c TenantContext) DebugExpires() (expires time.Time, ok bool) {
+	if c.Debug() {
+		expires, ok = c.Time(CtxKeyDebugExpires)
+	}
+	return
+}
+
+// EnableDebug turns on debugging, a non-zero duration sets an expiry relative
+// to now
+func (c TenantContext) EnableDebug(duration time.Duration) {
+	c[CtxKeyDebug] = "true"
+	if duration > 0 {
+		c[CtxKeyDebugExpires] = time.Now().Add(duration).UTC().Format(time.RFC3339)
+	} else {
+		delete(c, CtxKeyDebugExpires)
+	}
+}
+
+func (c TenantContext) DisableDebug() {
+	c[CtxKeyDebug] = "false"
+	delete(c, CtxKeyDebugExpires)
+}
+
+func (c TenantContext) AllowedUnlicensed() (allowed bool) {
+	return c.Bool(CtxKeyAllowedUnlicensed)
+}
+
+// UnlicensedExpires returns the time the unlicensed grant will be revoked
+// automatically, ok is false when no grant is present or has no expiry
+func (c TenantContext) UnlicensedExpires() (expires time.Time, ok bool) {
+	if c.AllowedUnlicensed() {
+		expires, ok = c.Time(CtxKeyUnlicensedExpires)
+	}
+	return
+}
+
+func (c TenantContext) UnlicensedReason() (reason string) {
+	if c.AllowedUnlicensed() {
+		reason = c.String(CtxKeyUnlicensedReason)
+	}
+	return
+}
+
+// AllowUnlicensed grants unlicensed access, a non-zero duration sets an expiry
+// relative to now
+func (c TenantContext) AllowUnlicensed(duration time.Duration, reason string) {
+	c[CtxKeyAllowedUnlicensed] = true
+	if duration > 0 {
+		c[CtxKeyUnlicensedExpires] = time.Now().Add(duration).UTC().Format(time.RFC3339)
+	} else {
+		delete(c, CtxKeyUnlicensedExpires)
+	}
+	if reason != "" {
+		c[CtxKeyUnlicensedReason] = reason
+	} else {
+		delete(c, CtxKeyUnlicensedReason)
+	}
+	delete(c, CtxKeyReject)
+}
+
+func (c TenantContext) RejectUnlicensed() {
+	c[CtxKeyAllowedUnlicensed] = false
+	delete(c, CtxKeyUnlicensedExpires)
+	delete(c, CtxKeyUnlicensedReason)
+}
+
+// Rejected returns the reason the tenant is rejected automatically by
+// features-gonnectian, which sets and removes this for unlicensed tenants
+func (c TenantContext) Rejected() (reason string, ok bool) {
+	reason = c.String(CtxKeyReject)
+	ok = reason != ""
+	return
+}
+
+// Flagged reports whether the tenant was flagged by an operator. The flag is
+// a note for operators only: features-gonnectian decides whether to reject
+// each request from the license alone, so requests from flagged tenants are
+// not refused
+func (c TenantContext) Flagged() (flagged bool) {
+	return c.String(CtxKeyFlagged) != ""
+}
+
+func (c TenantContext) FlagReason() (reason string) {
+	return c.String(CtxKeyFlagged)
+}
+
+func (c TenantContext) FlaggedAt() (at time.Time, ok bool) {
+	return c.Time(CtxKeyFlaggedAt)
+}
+
+func (c TenantContext) Flag(reason string) {
+	if reason == "" {
+		reason = "flagged"
+	}
+	c[CtxKeyFlagged] = reason
+	c[CtxKeyFlaggedAt] = time.Now().UTC().Format(time.RFC3339)
+}
+
+func (c TenantContext) Unflag() {
+	delete(c, CtxKeyFlagged)
+	delete(c, CtxKeyFlaggedAt)
+}
+
+// SweepExpired disables any time-limited settings which have expired as of
+// the given time, returning a description of each change made
+func (c TenantContext) SweepExpired(now time.Time) (changes []string) {
+	if expires, ok := c.DebugExpires(); ok && !now.Before(expires) {
+		c.DisableDebug()
+		changes = append(changes, fmt.Sprintf("debug expired %v", expires.Format(TimeFormat)))
+	}
+	if expires, ok := c.UnlicensedExpires(); ok && !now.Before(expires) {
+		if reason := c.UnlicensedReason(); reason != "" {
+			changes = append(changes, fmt.Sprintf("unlicensed grant expired %v (%v)", expires.Format(TimeFormat), reason))
+		} else {
+			changes = append(changes, fmt.Sprintf("unlicensed grant expired %v", expires.Format(TimeFormat)))
+		}
+		c.RejectUnlicensed()
+	}
+	return
+}
+
+const TimeFormat = "2006-01-02 15:04 MST"
+
+// DiffContexts returns a line for each key added (+), removed (-) or changed
+// (~) between the two contexts, sorted by key
+func DiffContexts(before, after TenantContext) (lines []string) {
+	for _, k := range contextKeys(before, after) {
+		bv, bok := before[k]
+		av, aok := after[k]
+		switch {
+		case bok && !aok:
+			lines = append(lines, fmt.Sprintf("- %v: %v", k, formatValue(bv)))
+		case !bok && aok:
+			lines = append(lines, fmt.Sprintf("+ %v: %v", k, formatValue(av)))
+		case formatValue(bv) != formatValue(av):
+			lines = append(lines, fmt.Sprintf("~ %v: %v -> %v", k, formatValue(bv), formatValue(av)))
+		}
+	}
+	return
+}
+
+// contextKeys returns the sorted union of the keys of both contexts
+func contextKeys(a, b TenantContext) (keys []string) {
+	unique := make(map[string]struct{})
+	for k := range a {
+		unique[k] = struct{}{}
+	}
+	for k := range b {
+		unique[k] = struct{}{}
+	}
+	for k := range unique {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func formatValue(v interface{}) (text string) {
+	if b, err := json.Marshal(v); err == nil {
+		text = string(b)
+	} else {
+		text = fmt.Sprintf("%v", v)
+	}
+	return
+}
+
+// FormatRemaining returns a short human-readable duration until the given time
+func FormatRemaining(until time.Time) (text string) {
+	d := time.Until(until)
+	switch {
+	case d <= 0:
+		text = "expired"
+	case d < time.Minute:
+		text = "<1m left"
+	case d < time.Hour:
+		text = fmt.Sprintf("%dm left", int(d.Minutes()))
+	case d < 48*time.Hour:
+		text = fmt.Sprintf("%dh%02dm left", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		text = fmt.Sprintf("%dd%02dh left", int(d.Hours())/24, int(d.Hours())%24)
+	}
+	return
+}
+
+// ParseExpiry parses durations like "90m", "24h" or "7d", or a calendar date
+// in the form "2006-01-02" (midnight local time)
+func ParseExpiry(input string) (duration time.Duration, err error) {
+	var days int
+	if _, e := fmt.Sscanf(input, "%dd", &days); e == nil && fmt.Sprintf("%dd", days) == input {
+		duration = time.Duration(days) * 24 * time.Hour
+	} else if date, e := time.ParseInLocation("2006-01-02", input, time.Local); e == nil {
+		duration = time.Until(date)
+	} else if duration, err = time.ParseDuration(input); err != nil {
+		err = fmt.Errorf("invalid expiry %q: expected forms like 90m, 24h, 7d or 2006-01-02", input)
+		return
+	}
+	if duration <= 0 {
+		err = fmt.Errorf("expiry must be in the future: %q", input)
+	}
+	return
+}
@@ -0,0 +1,188 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	inTwoDays := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	for _, test := range []struct {
+		input    string
+		expected time.Duration
+		invalid  bool
+	}{
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "24h", expected: 24 * time.Hour},
+		{input: "1h30m", expected: 90 * time.Minute},
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: inTwoDays},
+		{input: "7days", invalid: true},
+		{input: "d", invalid: true},
+		{input: "-1h", invalid: true},
+		{input: "0d", invalid: true},
+		{input: "2000-01-01", invalid: true},
+		{input: "", invalid: true},
+	} {
+		duration, err := ParseExpiry(test.input)
+		switch {
+		case test.invalid:
+			if err == nil {
+				t.Errorf("%q: expected an error, found %v", test.input, duration)
+			}
+		case err != nil:
+			t.Errorf("%q: %v", test.input, err)
+		case test.expected == 0:
+			// a calendar date is relative to now, only check it is ahead
+			if duration <= 24*time.Hour || duration > 72*time.Hour {
+				t.Errorf("%q: unexpected duration %v", test.input, duration)
+			}
+		case duration != test.expected:
+			t.Errorf("%q: expected %v, found %v", test.input, test.expected, duration)
+		}
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	// the margin keeps each case within its bucket while the test runs
+	margin := 30 * time.Second
+	for _, test := range []struct {
+		remaining time.Duration
+		expected  string
+	}{
+		{remaining: -time.Hour, expected: "expired"},
+		{remaining: 0, expected: "expired"},
+		{remaining: margin, expected: "<1m left"},
+		{remaining: 5*time.Minute + margin, expected: "5m left"},
+		{remaining: 90*time.Minute + margin, expected: "1h30m left"},
+		{remaining: 47*time.Hour + margin, expected: "47h00m left"},
+		{remaining: 3*24*time.Hour + 5*time.Hour + margin, expected: "3d05h left"},
+	} {
+		if text := FormatRemaining(time.Now().Add(test.remaining)); text != test.expected {
+			t.Errorf("%v: expected %q, found %q", test.remaining, test.expected, text)
+		}
+	}
+}
+
+func TestDiffContexts(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		before, after TenantContext
+		expected      []string
+	}{
+		{name: "equal", before: TenantContext{"debug": "true"}, after: TenantContext{"debug": "true"}},
+		{name: "empty", before: TenantContext{}, after: TenantContext{}},
+		{
+			name:     "added",
+			before:   TenantContext{},
+			after:    TenantContext{"debug": "true", "allowed-unlicensed": true},
+			expected: []string{`+ allowed-unlicensed: true`, `+ debug: "true"`},
+		},
+		{
+			name:     "removed",
+			before:   TenantContext{"reject": "unlicensed"},
+			after:    TenantContext{},
+			expected: []string{`- reject: "unlicensed"`},
+		},
+		{
+			name:     "changed",
+			before:   TenantContext{"debug": "false", "license": "none", "a": 1.0},
+			after:    TenantContext{"debug": "true", "license": "none", "a": 2.0},
+			expected: []string{`~ a: 1 -> 2`, `~ debug: "false" -> "true"`},
+		},
+		{
+			name:     "nested",
+			before:   TenantContext{"custom": map[string]interface{}{"k": "v"}},
+			after:    TenantContext{"custom": map[string]interface{}{"k": "w"}},
+			expected: []string{`~ custom: {"k":"v"} -> {"k":"w"}`},
+		},
+	} {
+		if lines := DiffContexts(test.before, test.after); !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("%v: expected %q, found %q", test.name, test.expected, lines)
+		}
+	}
+}
+
+func TestSweepExpired(t *testing.T) {
+	now := time.Date(2023, 6, 10, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name     string
+		ctx      TenantContext
+		expected TenantContext
+		changes  []string
+	}{
+		{
+			name:     "debug expired",
+			ctx:      TenantContext{"debug": "true", "debug-expires": "2023-06-10T11:00:00Z", "license": "active"},
+			expected: TenantContext{"debug": "false", "license": "active"},
+			changes:  []string{"debug expired 2023-06-10 11:00 UTC"},
+		},
+		{
+			name:     "debug expires now",
+			ctx:      TenantContext{"debug": "true", "debug-expires": "2023-06-10T12:00:00Z"},
+			expected: TenantContext{"debug": "false"},
+			changes:  []string{"debug expired 2023-06-10 12:00 UTC"},
+		},
+		{
+			name:     "debug not yet expired",
+			ctx:      TenantContext{"debug": "true", "debug-expires": "2023-06-10T12:01:00Z"},
+			expected: TenantContext{"debug": "true", "debug-expires": "2023-06-10T12:01:00Z"},
+		},
+		{
+			name:     "debug malformed expiry",
+			ctx:      TenantContext{"debug": "true", "debug-expires": "tomorrow"},
+			expected: TenantContext{"debug": "true", "debug-expires": "tomorrow"},
+		},
+		{
+			name:     "debug disabled",
+			ctx:      TenantContext{"debug": "false", "debug-expires": "2023-06-01T00:00:00Z"},
+			expected: TenantContext{"debug": "false", "debug-expires": "2023-06-01T00:00:00Z"},
+		},
+		{
+			name:     "unlicensed expired",
+			ctx:      TenantContext{"allowed-unlicensed": true, "allowed-unlicensed-expires": "2023-06-01T00:00:00Z", "allowed-unlicensed-reason": "trial"},
+			expected: TenantContext{"allowed-unlicensed": false},
+			changes:  []string{"unlicensed grant expired 2023-06-01 00:00 UTC (trial)"},
+		},
+		{
+			name:     "unlicensed not yet expired",
+			ctx:      TenantContext{"allowed-unlicensed": true, "allowed-unlicensed-expires": "2023-07-01T00:00:00Z"},
+			expected: TenantContext{"allowed-unlicensed": true, "allowed-unlicensed-expires": "2023-07-01T00:00:00Z"},
+		},
+		{
+			name:     "unlicensed malformed expiry",
+			ctx:      TenantContext{"allowed-unlicensed": true, "allowed-unlicensed-expires": 1686398400},
+			expected: TenantContext{"allowed-unlicensed": true, "allowed-unlicensed-expires": 1686398400},
+		},
+		{
+			name:     "both expired",
+			ctx:      TenantContext{"debug": "true", "debug-expires": "2023-06-09T00:00:00Z", "allowed-unlicensed": true, "allowed-unlicensed-expires": "2023-06-09T00:00:00Z"},
+			expected: TenantContext{"debug": "false", "allowed-unlicensed": false},
+			changes:  []string{"debug expired 2023-06-09 00:00 UTC", "unlicensed grant expired 2023-06-09 00:00 UTC"},
+		},
+	} {
+		if changes := test.ctx.SweepExpired(now); !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("%v: expected changes %q, found %q", test.name, test.changes, changes)
+		}
+		if !reflect.DeepEqual(test.ctx, test.expected) {
+			t.Errorf("%v: expected %v, found %v", test.name, test.expected, test.ctx)
+		}
+	}
+}
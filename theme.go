@@ -0,0 +1,418 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-curses/cdk/lib/paint"
+	"github.com/go-curses/ctk"
+)
+
+// DefaultThemeName is the preset of the built-in colors
+const DefaultThemeName = "default"
+
+var (
+	// ThemeFlag is the global flag used to select the console theme
+	ThemeFlag = &cli.StringFlag{
+		Name:    "gonnectian-theme",
+		Usage:   "console theme: a preset name (" + strings.Join(ThemePresetNames(), ", ") + "), a YAML/JSON theme file or inline YAML/JSON",
+		EnvVars: []string{"GONNECTIAN_THEME"},
+	}
+)
+
+// ThemeStyle is a color and attribute definition, colors are W3C names or
+// "#rrggbb" hex values and empty colors use the terminal default
+type ThemeStyle struct {
+	Fg        string `json:"fg,omitempty" yaml:"fg,omitempty"`
+	Bg        string `json:"bg,omitempty" yaml:"bg,omitempty"`
+	Bold      bool   `json:"bold,omitempty" yaml:"bold,omitempty"`
+	Dim       bool   `json:"dim,omitempty" yaml:"dim,omitempty"`
+	Reverse   bool   `json:"reverse,omitempty" yaml:"reverse,omitempty"`
+	Underline bool   `json:"underline,omitempty" yaml:"underline,omitempty"`
+}
+
+// Style returns the paint.Style of the definition
+func (s *ThemeStyle) Style() (style paint.Style, err error) {
+	fg, bg := paint.ColorDefault, paint.ColorDefault
+	if fg, err = parseThemeColor(s.Fg); err != nil {
+		return
+	} else if bg, err = parseThemeColor(s.Bg); err != nil {
+		return
+	}
+	style = paint.StyleDefault.
+		Foreground(fg).
+		Background(bg).
+		Bold(s.Bold).
+		Dim(s.Dim).
+		Reverse(s.Reverse).
+		Underline(s.Underline)
+	return
+}
+
+func parseThemeColor(name string) (color paint.Color, err error) {
+	if name == "" {
+		return paint.ColorDefault, nil
+	}
+	var ok bool
+	if color, ok = paint.ParseColor(strings.ToLower(name)); !ok {
+		err = fmt.Errorf("unknown theme color: %q", name)
+	}
+	return
+}
+
+// ConsoleTheme defines the console colors, for example:
+//
+//	name: solarized
+//	base: light
+//	toggle: { fg: white, bg: "#268bd2", bold: true }
+//
+// Styles which are not given are taken from the Base preset, or the default
+// preset when no Base is given
+type ConsoleTheme struct {
+	Name string `json:"name" yaml:"name"`
+	Base string `json:"base,omitempty" yaml:"base,omitempty"`
+
+	// Text is used by windows, frames and labels
+	Text *ThemeStyle `json:"text,omitempty" yaml:"text,omitempty"`
+	// Border is used by window and frame borders
+	Border *ThemeStyle `json:"border,omitempty" yaml:"border,omitempty"`
+	// Button is used by all buttons
+	Button *ThemeStyle `json:"button,omitempty" yaml:"button,omitempty"`
+	// ButtonFocus is used by buttons with the focus or under the mouse
+	ButtonFocus *ThemeStyle `json:"buttonFocus,omitempty" yaml:"buttonFocus,omitempty"`
+	// Toggle is used by the toggle button of the active panel
+	Toggle *ThemeStyle `json:"toggle,omitempty" yaml:"toggle,omitempty"`
+	// Insensitive is used by disabled buttons
+	Insensitive *ThemeStyle `json:"insensitive,omitempty" yaml:"insensitive,omitempty"`
+	// Scrollbar is used by scrollbar sliders
+	Scrollbar *ThemeStyle `json:"scrollbar,omitempty" yaml:"scrollbar,omitempty"`
+}
+
+// ThemePresets are the built-in themes, the default preset approximates the
+// built-in colors for use as a Base and selecting it restores them exactly
+var ThemePresets = map[string]*ConsoleTheme{
+	DefaultThemeName: {
+		Name:        DefaultThemeName,
+		Text:        &ThemeStyle{Fg: "white", Bg: "navy"},
+		Border:      &ThemeStyle{Fg: "white", Bg: "navy"},
+		Button:      &ThemeStyle{Fg: "white", Bg: "firebrick", Dim: true},
+		ButtonFocus: &ThemeStyle{Fg: "white", Bg: "darkred", Bold: true},
+		Toggle:      &ThemeStyle{Fg: "white", Bg: "forestgreen", Bold: true},
+		Insensitive: &ThemeStyle{Fg: "darkslategray", Bg: "rosybrown", Dim: true},
+		Scrollbar:   &ThemeStyle{Fg: "black", Bg: "silver"},
+	},
+	"dark": {
+		Name:        "dark",
+		Text:        &ThemeStyle{Fg: "silver", Bg: "black"},
+		Border:      &ThemeStyle{Fg: "gray", Bg: "black"},
+		Button:      &ThemeStyle{Fg: "white", Bg: "dimgray"},
+		ButtonFocus: &ThemeStyle{Fg: "black", Bg: "silver", Bold: true},
+		Toggle:      &ThemeStyle{Fg: "white", Bg: "steelblue", Bold: true},
+		Insensitive: &ThemeStyle{Fg: "gray", Bg: "black", Dim: true},
+		Scrollbar:   &ThemeStyle{Fg: "black", Bg: "gray"},
+	},
+	"light": {
+		Name:        "light",
+		Text:        &ThemeStyle{Fg: "black", Bg: "white"},
+		Border:      &ThemeStyle{Fg: "dimgray", Bg: "white"},
+		Button:      &ThemeStyle{Fg: "black", Bg: "lightgray"},
+		ButtonFocus: &ThemeStyle{Fg: "white", Bg: "navy", Bold: true},
+		Toggle:      &ThemeStyle{Fg: "black", Bg: "lightskyblue", Bold: true},
+		Insensitive: &ThemeStyle{Fg: "gray", Bg: "whitesmoke"},
+		Scrollbar:   &ThemeStyle{Fg: "white", Bg: "gray"},
+	},
+	"high-contrast": {
+		Name:        "high-contrast",
+		Text:        &ThemeStyle{Fg: "white", Bg: "black", Bold: true},
+		Border:      &ThemeStyle{Fg: "yellow", Bg: "black", Bold: true},
+		Button:      &ThemeStyle{Fg: "black", Bg: "yellow", Bold: true},
+		ButtonFocus: &ThemeStyle{Fg: "black", Bg: "white", Bold: true, Underline: true},
+		Toggle:      &ThemeStyle{Fg: "black", Bg: "aqua", Bold: true, Underline: true},
+		Insensitive: &ThemeStyle{Fg: "silver", Bg: "black"},
+		Scrollbar:   &ThemeStyle{Fg: "black", Bg: "white"},
+	},
+	"monochrome": {
+		Name:        "monochrome",
+		Text:        &ThemeStyle{},
+		Border:      &ThemeStyle{},
+		Button:      &ThemeStyle{},
+		ButtonFocus: &ThemeStyle{Reverse: true, Underline: true},
+		Toggle:      &ThemeStyle{Reverse: true, Bold: true},
+		Insensitive: &ThemeStyle{Dim: true},
+		Scrollbar:   &ThemeStyle{Reverse: true},
+	},
+}
+
+func ThemePresetNames() (names []string) {
+	for name := range ThemePresets {
+		if name != DefaultThemeName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{DefaultThemeName}, names...)
+	return
+}
+
+// LoadTheme returns the preset with the given name, or the theme read from
+// the YAML or JSON file at the given path, named after the file unless the
+// theme has a name, or the theme parsed from the value as inline YAML or JSON
+func LoadTheme(value string) (theme *ConsoleTheme, err error) {
+	if preset, ok := ThemePresets[value]; ok {
+		return preset, nil
+	}
+	data := []byte(value)
+	name := "custom"
+	if info, e := os.Stat(value); e == nil && !info.IsDir() {
+		if data, err = os.ReadFile(value); err != nil {
+			return
+		}
+		name = info.Name()
+		switch ext := filepath.Ext(name); ext {
+		case ".yaml", ".yml", ".json":
+			name = strings.TrimSuffix(name, ext)
+		}
+	} else if !strings.ContainsAny(value, ":{") {
+		err = fmt.Errorf("theme preset or file not found: %q", value)
+		return
+	}
+	theme = &ConsoleTheme{}
+	if err = yaml.Unmarshal(data, theme); err != nil {
+		err = fmt.Errorf("error parsing theme: %v", err)
+		return
+	}
+	if theme.Name == "" {
+		theme.Name = name
+	}
+	if _, err = theme.resolve(); err != nil {
+		theme = nil
+	}
+	return
+}
+
+// resolve returns a copy of the theme with all styles set from the Base
+// preset where missing
+func (t *ConsoleTheme) resolve() (resolved ConsoleTheme, err error) {
+	resolved = *t
+	baseName := t.Base
+	if baseName == "" {
+		baseName = DefaultThemeName
+	}
+	if baseName != t.Name {
+		base, ok := ThemePresets[baseName]
+		if !ok {
+			return resolved, fmt.Errorf("theme base preset not found: %q", baseName)
+		}
+		for _, pair := range []struct{ dst, src **ThemeStyle }{
+			{&resolved.Text, &base.Text},
+			{&resolved.Border, &base.Border},
+			{&resolved.Button, &base.Button},
+			{&resolved.ButtonFocus, &base.ButtonFocus},
+			{&resolved.Toggle, &base.Toggle},
+			{&resolved.Insensitive, &base.Insensitive},
+			{&resolved.Scrollbar, &base.Scrollbar},
+		} {
+			if *pair.dst == nil {
+				*pair.dst = *pair.src
+			}
+		}
+	}
+	for _, s := range []*ThemeStyle{resolved.Text, resolved.Border, resolved.Button, resolved.ButtonFocus, resolved.Toggle, resolved.Insensitive, resolved.Scrollbar} {
+		if s == nil {
+			return resolved, fmt.Errorf("theme %q is incomplete", t.Name)
+		} else if _, err = s.Style(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// themedNames are the registered themes replaced by a ConsoleTheme
+var themedNames = []paint.ThemeName{
+	paint.ColorTheme,
+	ctk.LabelColorTheme,
+	ctk.ButtonColorTheme,
+	ctk.ScrollbarColorTheme,
+	ButtonActiveTheme,
+	PanelFirstFrameTheme,
+	PanelDefaultFrameTheme,
+}
+
+var (
+	gOriginalThemes     map[paint.ThemeName]paint.Theme
+	gOriginalThemesOnce sync.Once
+)
+
+// RegisterConsoleTheme replaces the registered paint themes used by the
+// console with those of the given theme, the default preset restores the
+// built-in themes
+func RegisterConsoleTheme(theme *ConsoleTheme) (err error) {
+	gOriginalThemesOnce.Do(func() {
+		gOriginalThemes = make(map[paint.ThemeName]paint.Theme)
+		for _, name := range themedNames {
+			gOriginalThemes[name], _ = paint.GetTheme(name)
+		}
+	})
+	if theme == ThemePresets[DefaultThemeName] {
+		for name, original := range gOriginalThemes {
+			paint.RegisterTheme(name, original)
+		}
+		return
+	}
+
+	var resolved ConsoleTheme
+	if resolved, err = theme.resolve(); err != nil {
+		return
+	}
+	text, _ := resolved.Text.Style()
+	border, _ := resolved.Border.Style()
+	button, _ := resolved.Button.Style()
+	focus, _ := resolved.ButtonFocus.Style()
+	toggle, _ := resolved.Toggle.Style()
+	insensitive, _ := resolved.Insensitive.Style()
+	scrollbar, _ := resolved.Scrollbar.Style()
+
+	aspect := func(original paint.ThemeAspect, normal, active, insensitive paint.Style) paint.ThemeAspect {
+		original.Normal = normal
+		original.Selected = active
+		original.Active = active
+		original.Prelight = active
+		original.Insensitive = insensitive
+		return original
+	}
+	themed := func(name paint.ThemeName, content, border [3]paint.Style) {
+		original := gOriginalThemes[name]
+		paint.RegisterTheme(name, paint.Theme{
+			Content: aspect(original.Content, content[0], content[1], content[2]),
+			Border:  aspect(original.Border, border[0], border[1], border[2]),
+		})
+	}
+	themed(paint.ColorTheme, [3]paint.Style{text, text, text}, [3]paint.Style{border, border, border})
+	themed(ctk.LabelColorTheme, [3]paint.Style{text, text, text}, [3]paint.Style{border, border, border})
+	themed(PanelFirstFrameTheme, [3]paint.Style{text, text, text}, [3]paint.Style{border, border, border})
+	themed(PanelDefaultFrameTheme, [3]paint.Style{text, text, text}, [3]paint.Style{border, border, border})
+	themed(ctk.ButtonColorTheme, [3]paint.Style{button, focus, insensitive}, [3]paint.Style{button, focus, insensitive})
+	themed(ButtonActiveTheme, [3]paint.Style{toggle, toggle.Reverse(true), insensitive}, [3]paint.Style{toggle, toggle.Reverse(true), insensitive})
+	themed(ctk.ScrollbarColorTheme, [3]paint.Style{scrollbar, scrollbar.Bold(true), insensitive}, [3]paint.Style{text, text, text})
+	return
+}
+
+// ThemedPanel is implemented by panels which keep registered themes for
+// widgets created during Refresh
+type ThemedPanel interface {
+	ThemeChanged()
+}
+
+// applyTheme registers the theme and re-themes all existing widgets
+func (c *CCurses) applyTheme(theme *ConsoleTheme) (err error) {
+	if err = RegisterConsoleTheme(theme); err != nil {
+		return
+	}
+	c.theme = theme
+	c.defaultToggleTheme, _ = paint.GetTheme(ctk.ButtonColorTheme)
+	c.activeToggleTheme, _ = paint.GetTheme(ButtonActiveTheme)
+	defaultTheme, _ := paint.GetTheme(paint.ColorTheme)
+	c.window.SetTheme(defaultTheme)
+	c.rethemeWidget(c.window.GetVBox())
+	for _, panel := range c.panels {
+		if p, ok := panel.(ThemedPanel); ok {
+			p.ThemeChanged()
+		}
+	}
+	return
+}
+
+func (c *CCurses) rethemeWidget(w ctk.Widget) {
+	get := func(name paint.ThemeName) (theme paint.Theme) {
+		theme, _ = paint.GetTheme(name)
+		return
+	}
+	switch v := w.(type) {
+	case ctk.Button:
+		// buttons theme their own children
+		v.SetTheme(get(ctk.ButtonColorTheme))
+		return
+	case ctk.Label:
+		v.SetTheme(get(ctk.LabelColorTheme))
+	case ctk.Frame:
+		v.SetTheme(get(paint.ColorTheme))
+		if label := v.GetLabelWidget(); label != nil {
+			label.SetTheme(get(ctk.LabelColorTheme))
+		}
+	case ctk.ScrolledViewport:
+		v.SetTheme(get(paint.ColorTheme))
+		if sb := v.GetVScrollbar(); sb != nil {
+			sb.SetTheme(get(ctk.ScrollbarColorTheme))
+		}
+		if sb := v.GetHScrollbar(); sb != nil {
+			sb.SetTheme(get(ctk.ScrollbarColorTheme))
+		}
+	default:
+		w.SetTheme(get(paint.ColorTheme))
+	}
+	if container, ok := w.(ctk.Container); ok {
+		for _, child := range container.GetChildren() {
+			c.rethemeWidget(child)
+		}
+	}
+}
+
+// themeHandler presents the theme presets, and any theme loaded at startup,
+// for switching themes at runtime
+func (c *CCurses) themeHandler() {
+	themes := make(map[string]*ConsoleTheme)
+	names := ThemePresetNames()
+	for _, name := range names {
+		themes[name] = ThemePresets[name]
+	}
+	if loaded := c.console.themeLoaded; loaded != nil {
+		if _, ok := themes[loaded.Name]; !ok {
+			names = append(names, loaded.Name)
+		}
+		themes[loaded.Name] = loaded
+	}
+	var options []string
+	for _, name := range names {
+		if c.theme != nil && c.theme.Name == name || c.theme == nil && name == DefaultThemeName {
+			options = append(options, name+" (current)")
+		} else {
+			options = append(options, name)
+		}
+	}
+	c.promptChoice("Theme", "Select the console theme:", options, func(idx int) {
+		theme := themes[names[idx]]
+		if err := c.applyTheme(theme); err != nil {
+			c.notify("Theme Failed", err.Error())
+			return
+		}
+		if theme == c.console.themeLoaded && ThemePresets[theme.Name] != theme {
+			c.console.prefs.Theme = c.console.themeValue
+		} else {
+			c.console.prefs.Theme = theme.Name
+		}
+		c.Refresh()
+	})
+}
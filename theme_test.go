@@ -0,0 +1,43 @@
+//go:build curses || all
+
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonnectian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadThemeFileName(t *testing.T) {
+	dir := t.TempDir()
+	for file, expected := range map[string]string{
+		"ocean.yaml":  "ocean",
+		"ocean.yml":   "ocean",
+		"ocean.json":  "ocean",
+		"ocean.theme": "ocean.theme",
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte(`{"base": "high-contrast"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if theme, err := LoadTheme(path); err != nil {
+			t.Errorf("%v: %v", file, err)
+		} else if theme.Name != expected {
+			t.Errorf("%v: expected the theme named %q, found %q", file, expected, theme.Name)
+		}
+	}
+}